@@ -4,27 +4,60 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/ibuildthecloud/klum/pkg/controllers/user"
 	"github.com/ibuildthecloud/klum/pkg/crd"
+	"github.com/ibuildthecloud/klum/pkg/credential"
+	"github.com/ibuildthecloud/klum/pkg/encryption"
 	"github.com/ibuildthecloud/klum/pkg/generated/controllers/klum.cattle.io"
+	"github.com/ibuildthecloud/klum/pkg/webhook"
 	"github.com/rancher/wrangler-api/pkg/generated/controllers/core"
 	"github.com/rancher/wrangler-api/pkg/generated/controllers/rbac"
 	"github.com/rancher/wrangler/pkg/apply"
 	"github.com/rancher/wrangler/pkg/kubeconfig"
+	"github.com/rancher/wrangler/pkg/schemes"
 	"github.com/rancher/wrangler/pkg/signals"
 	"github.com/rancher/wrangler/pkg/start"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 )
 
 var (
-	Version    = "v0.0.0-dev"
-	GitCommit  = "HEAD"
-	cfg        user.Config
-	kubeConfig string
+	Version        = "v0.0.0-dev"
+	GitCommit      = "HEAD"
+	cfg            user.Config
+	kubeConfig     string
+	homeQuota      string
+	homeLimits     string
+	tokenAudiences string
+	signerSecret   string
+	signerCertFile string
+	signerKeyFile  string
+
+	webhookConfig            webhook.Config
+	controllerServiceAccount string
+
+	credentialConfig credential.Config
+	credentialServer string
+	credentialUser   string
+	refreshToken     string
+	caFile           string
+
+	identityFile string
+	passphrase   string
 )
 
 func main() {
@@ -72,8 +105,268 @@ func main() {
 			Value:       "cluster-admin",
 			Destination: &cfg.DefaultClusterRole,
 		},
+		cli.BoolFlag{
+			Name:        "home-namespaces",
+			Usage:       "Create a home namespace for every user, not just users with spec.homeNamespace",
+			EnvVar:      "HOME_NAMESPACES",
+			Destination: &cfg.HomeNamespaces,
+		},
+		cli.StringFlag{
+			Name:        "home-namespace-prefix",
+			Usage:       "Prefix of the name of home namespaces",
+			EnvVar:      "HOME_NAMESPACE_PREFIX",
+			Value:       "user-",
+			Destination: &cfg.HomeNamespacePrefix,
+		},
+		cli.StringFlag{
+			Name:        "home-namespace-quota",
+			Usage:       "Default resource quota of home namespaces, such as requests.cpu=4,pods=20",
+			EnvVar:      "HOME_NAMESPACE_QUOTA",
+			Destination: &homeQuota,
+		},
+		cli.StringFlag{
+			Name:        "home-namespace-limits",
+			Usage:       "Default container limits of home namespaces, such as cpu=500m,memory=512Mi",
+			EnvVar:      "HOME_NAMESPACE_LIMITS",
+			Destination: &homeLimits,
+		},
+		cli.BoolFlag{
+			Name:        "effective-permissions",
+			Usage:       "Report the rules granted to every user in a UserPermissions object",
+			EnvVar:      "EFFECTIVE_PERMISSIONS",
+			Destination: &cfg.EffectivePermissions,
+		},
+		cli.StringFlag{
+			Name:        "credential-mode",
+			Usage:       "How credentials in Kubeconfigs are issued, secret, token-request, certificate, ca, exec or oidc",
+			EnvVar:      "CREDENTIAL_MODE",
+			Value:       user.CredentialModeSecret,
+			Destination: &cfg.CredentialMode,
+		},
+		cli.DurationFlag{
+			Name:        "token-lifetime",
+			Usage:       "Lifetime of tokens issued in token-request and exec mode, in token-request mode they are renewed before they expire",
+			EnvVar:      "TOKEN_LIFETIME",
+			Value:       24 * time.Hour,
+			Destination: &cfg.TokenLifetime,
+		},
+		cli.StringFlag{
+			Name:        "token-audiences",
+			Usage:       "Comma separated audiences of tokens issued in token-request and exec mode, defaults to the audience of the apiserver",
+			EnvVar:      "TOKEN_AUDIENCES",
+			Destination: &tokenAudiences,
+		},
+		cli.DurationFlag{
+			Name:        "certificate-lifetime",
+			Usage:       "Lifetime of client certificates issued in certificate and ca mode, they are renewed before they expire",
+			EnvVar:      "CERTIFICATE_LIFETIME",
+			Value:       30 * 24 * time.Hour,
+			Destination: &cfg.CertificateLifetime,
+		},
+		cli.StringFlag{
+			Name:        "signer-secret",
+			Usage:       "TLS secret in the namespace holding the client CA certificate and key used in ca mode",
+			EnvVar:      "SIGNER_SECRET",
+			Destination: &signerSecret,
+		},
+		cli.StringFlag{
+			Name:        "signer-cert-file",
+			Usage:       "File with the client CA certificate used in ca mode",
+			EnvVar:      "SIGNER_CERT_FILE",
+			Destination: &signerCertFile,
+		},
+		cli.StringFlag{
+			Name:        "signer-key-file",
+			Usage:       "File with the client CA key used in ca mode",
+			EnvVar:      "SIGNER_KEY_FILE",
+			Destination: &signerKeyFile,
+		},
+		cli.StringFlag{
+			Name:        "credential-server",
+			Usage:       "External URL of the credential server put in Kubeconfigs in exec mode",
+			EnvVar:      "CREDENTIAL_SERVER",
+			Destination: &cfg.CredentialServer,
+		},
+		cli.StringFlag{
+			Name:        "credential-listen",
+			Usage:       "Address to serve the credential server on in exec mode",
+			EnvVar:      "CREDENTIAL_LISTEN",
+			Value:       ":8444",
+			Destination: &credentialConfig.Listen,
+		},
+		cli.StringFlag{
+			Name:        "credential-cert-file",
+			Usage:       "Serving certificate of the credential server",
+			EnvVar:      "CREDENTIAL_CERT_FILE",
+			Destination: &credentialConfig.CertFile,
+		},
+		cli.StringFlag{
+			Name:        "credential-key-file",
+			Usage:       "Key of the serving certificate of the credential server",
+			EnvVar:      "CREDENTIAL_KEY_FILE",
+			Destination: &credentialConfig.KeyFile,
+		},
+		cli.BoolFlag{
+			Name:        "credential-insecure",
+			Usage:       "Serve the credential server over plain HTTP without a certificate, only behind a proxy terminating TLS",
+			EnvVar:      "CREDENTIAL_INSECURE",
+			Destination: &credentialConfig.Insecure,
+		},
+		cli.StringFlag{
+			Name:        "oidc-issuer-url",
+			Usage:       "URL of the OIDC issuer the cluster trusts, put in Kubeconfigs in oidc mode",
+			EnvVar:      "OIDC_ISSUER_URL",
+			Destination: &cfg.OIDC.IssuerURL,
+		},
+		cli.StringFlag{
+			Name:        "oidc-client-id",
+			Usage:       "OIDC client ID put in Kubeconfigs in oidc mode",
+			EnvVar:      "OIDC_CLIENT_ID",
+			Destination: &cfg.OIDC.ClientID,
+		},
+		cli.StringFlag{
+			Name:        "oidc-client-secret",
+			Usage:       "OIDC client secret put in Kubeconfigs in oidc mode, for clients that aren't public",
+			EnvVar:      "OIDC_CLIENT_SECRET",
+			Destination: &cfg.OIDC.ClientSecret,
+		},
+		cli.StringFlag{
+			Name:        "oidc-username-claim",
+			Usage:       "Claim the apiserver takes the username from, sub or email",
+			EnvVar:      "OIDC_USERNAME_CLAIM",
+			Value:       "sub",
+			Destination: &cfg.OIDC.UsernameClaim,
+		},
+		cli.StringFlag{
+			Name:        "oidc-username-prefix",
+			Usage:       "Prefix the apiserver adds to OIDC usernames, like the apiserver it defaults to the issuer URL and # for claims other than email and - disables it",
+			EnvVar:      "OIDC_USERNAME_PREFIX",
+			Destination: &cfg.OIDC.UsernamePrefix,
+		},
+		cli.StringFlag{
+			Name:        "oidc-groups-prefix",
+			Usage:       "Prefix the apiserver adds to OIDC groups",
+			EnvVar:      "OIDC_GROUPS_PREFIX",
+			Destination: &cfg.OIDC.GroupsPrefix,
+		},
+		cli.BoolFlag{
+			Name:        "oidc-auth-provider",
+			Usage:       "Put the oidc auth-provider of kubectl in Kubeconfigs instead of an exec block for kubelogin",
+			EnvVar:      "OIDC_AUTH_PROVIDER",
+			Destination: &cfg.OIDC.AuthProvider,
+		},
+		cli.StringFlag{
+			Name:        "kubeconfig-storage",
+			Usage:       "Where Kubeconfigs with their credentials are stored, resource or secret",
+			EnvVar:      "KUBECONFIG_STORAGE",
+			Value:       user.KubeconfigStorageResource,
+			Destination: &cfg.KubeconfigStorage,
+		},
+		cli.BoolFlag{
+			Name:        "home-namespace-secrets",
+			Usage:       "Store the Kubeconfig Secrets of users in their home namespaces instead of the namespace of the controller",
+			EnvVar:      "HOME_NAMESPACE_SECRETS",
+			Destination: &cfg.HomeNamespaceSecrets,
+		},
 	}
 	app.Action = run
+	app.Commands = []cli.Command{
+		{
+			Name:  "webhook",
+			Usage: "Serve the admission webhook validating and defaulting Users and protecting Kubeconfigs",
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:        "port",
+					Usage:       "Port to serve the webhook on",
+					EnvVar:      "WEBHOOK_PORT",
+					Value:       8443,
+					Destination: &webhookConfig.Port,
+				},
+				cli.StringFlag{
+					Name:        "service-name",
+					Usage:       "Name of the Service of the webhook in the namespace",
+					EnvVar:      "WEBHOOK_SERVICE_NAME",
+					Value:       "klum-webhook",
+					Destination: &webhookConfig.ServiceName,
+				},
+				cli.StringFlag{
+					Name:        "secret-name",
+					Usage:       "Name of the Secret to store the serving certificate in",
+					EnvVar:      "WEBHOOK_SECRET_NAME",
+					Value:       "klum-webhook-tls",
+					Destination: &webhookConfig.SecretName,
+				},
+				cli.StringFlag{
+					Name:        "failure-policy",
+					Usage:       "What the apiserver does when the webhook is unavailable, Fail or Ignore",
+					EnvVar:      "WEBHOOK_FAILURE_POLICY",
+					Value:       "Fail",
+					Destination: &webhookConfig.FailurePolicy,
+				},
+				cli.StringFlag{
+					Name:        "controller-service-account",
+					Usage:       "ServiceAccount the controller runs as, the only one allowed to change Kubeconfigs",
+					EnvVar:      "CONTROLLER_SERVICE_ACCOUNT",
+					Value:       "klum",
+					Destination: &controllerServiceAccount,
+				},
+			},
+			Action: runWebhook,
+		},
+		{
+			Name:  "credential",
+			Usage: "Print an ExecCredential with a token from the credential server, used as exec plugin in Kubeconfigs",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:        "server",
+					Usage:       "URL of the credential server",
+					Destination: &credentialServer,
+				},
+				cli.StringFlag{
+					Name:        "user",
+					Usage:       "Name of the user",
+					Destination: &credentialUser,
+				},
+				cli.StringFlag{
+					Name:        "refresh-token",
+					Usage:       "Refresh token of the user",
+					EnvVar:      user.RefreshTokenEnv,
+					Destination: &refreshToken,
+				},
+				cli.StringFlag{
+					Name:        "certificate-authority",
+					Usage:       "File with the CA of the credential server, if it isn't trusted by the system",
+					Destination: &caFile,
+				},
+			},
+			Action: runCredential,
+		},
+		{
+			Name:  "kubeconfig",
+			Usage: "Work with Kubeconfigs issued by klum",
+			Subcommands: []cli.Command{
+				{
+					Name:      "decrypt",
+					Usage:     "Decrypt a Kubeconfig encrypted to the public key of a user, read from FILE or stdin",
+					ArgsUsage: "[FILE]",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:        "identity, i",
+							Usage:       "File with the private key, an age identity file, an OpenSSH ed25519 key or an armored OpenPGP key",
+							Destination: &identityFile,
+						},
+						cli.StringFlag{
+							Name:        "passphrase",
+							Usage:       "Passphrase of an encrypted SSH or OpenPGP private key",
+							EnvVar:      "KLUM_PASSPHRASE",
+							Destination: &passphrase,
+						},
+					},
+					Action: runDecrypt,
+				},
+			},
+		},
+	}
 
 	if err := app.Run(os.Args); err != nil {
 		logrus.Fatal(err)
@@ -84,6 +377,10 @@ func run(c *cli.Context) error {
 	logrus.Info("Starting klum controller")
 	ctx := signals.SetupSignalContext()
 
+	if err := parseConfig(); err != nil {
+		return err
+	}
+
 	restConfig, err := kubeconfig.GetNonInteractiveClientConfig(kubeConfig).ClientConfig()
 	if err != nil {
 		return err
@@ -93,6 +390,18 @@ func run(c *cli.Context) error {
 		return err
 	}
 
+	discovery, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	cfg.TokenSecretsCreated, err = user.CreatesTokenSecrets(discovery)
+	if err != nil {
+		return err
+	}
+	if !cfg.TokenSecretsCreated && cfg.CredentialMode == user.CredentialModeSecret {
+		logrus.Info("Cluster does not create ServiceAccount token secrets, creating them for users")
+	}
+
 	core, err := core.NewFactoryFromConfig(restConfig)
 	if err != nil {
 		return err
@@ -113,20 +422,222 @@ func run(c *cli.Context) error {
 		return nil
 	}
 
+	coreClient, err := corev1.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+
+	if cfg.CredentialMode == user.CredentialModeCA {
+		cfg.Signer, err = loadSigner(coreClient)
+		if err != nil {
+			return fmt.Errorf("invalid client CA: %v", err)
+		}
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&corev1.EventSinkImpl{Interface: coreClient.Events("")})
+	recorder := broadcaster.NewRecorder(schemes.All, v1.EventSource{Component: "klum"})
+
 	user.Register(ctx,
 		cfg,
 		apply,
+		recorder,
+		coreClient,
+		dynamicClient,
 		core.Core().V1().ServiceAccount(),
+		rbac.Rbac().V1().ClusterRole(),
 		rbac.Rbac().V1().ClusterRoleBinding(),
+		rbac.Rbac().V1().Role(),
 		rbac.Rbac().V1().RoleBinding(),
 		core.Core().V1().Secret(),
+		core.Core().V1().Namespace(),
 		klum.Klum().V1alpha1().Kubeconfig(),
+		klum.Klum().V1alpha1().Group(),
+		klum.Klum().V1alpha1().AccessRequest(),
+		klum.Klum().V1alpha1().RoleTemplate(),
+		klum.Klum().V1alpha1().KlumPolicy(),
+		klum.Klum().V1alpha1().UserPermissions(),
+		klum.Klum().V1alpha1().Credential(),
 		klum.Klum().V1alpha1().User())
 
 	if err := start.All(ctx, 2, klum, core, rbac); err != nil {
 		logrus.Fatalf("Error starting: %s", err.Error())
 	}
 
+	if cfg.CredentialMode == user.CredentialModeExec {
+		credentialConfig.Namespace = cfg.Namespace
+		credentialConfig.TokenLifetime = cfg.TokenLifetime
+		credentialConfig.TokenAudiences = cfg.TokenAudiences
+		return credential.Run(ctx, credentialConfig, coreClient,
+			core.Core().V1().ServiceAccount().Cache(),
+			core.Core().V1().Secret().Cache())
+	}
+
 	<-ctx.Done()
 	return nil
 }
+
+// parseConfig checks the global options and parses the ones that aren't plain
+// values into cfg. The controller and the webhook share them, so that the webhook
+// validates Users like the controller handles them.
+func parseConfig() error {
+	var err error
+	cfg.HomeNamespaceQuota, err = user.ParseResourceList(homeQuota)
+	if err != nil {
+		return fmt.Errorf("invalid home namespace quota: %v", err)
+	}
+	cfg.HomeNamespaceLimits, err = user.ParseResourceList(homeLimits)
+	if err != nil {
+		return fmt.Errorf("invalid home namespace limits: %v", err)
+	}
+
+	switch cfg.CredentialMode {
+	case user.CredentialModeSecret, user.CredentialModeTokenRequest, user.CredentialModeCertificate, user.CredentialModeCA:
+	case user.CredentialModeExec:
+		if cfg.CredentialServer == "" {
+			return fmt.Errorf("exec mode needs the URL of the credential server")
+		}
+		if (credentialConfig.CertFile == "" || credentialConfig.KeyFile == "") && !credentialConfig.Insecure {
+			return fmt.Errorf("exec mode needs a serving certificate and key for the credential server, or --credential-insecure behind a proxy terminating TLS")
+		}
+	case user.CredentialModeOIDC:
+		if cfg.OIDC.IssuerURL == "" || cfg.OIDC.ClientID == "" {
+			return fmt.Errorf("oidc mode needs the issuer URL and client ID")
+		}
+		if cfg.OIDC.UsernameClaim != "sub" && cfg.OIDC.UsernameClaim != "email" {
+			return fmt.Errorf("invalid OIDC username claim %q, must be sub or email", cfg.OIDC.UsernameClaim)
+		}
+	default:
+		return fmt.Errorf("invalid credential mode %q", cfg.CredentialMode)
+	}
+	if cfg.KubeconfigStorage != user.KubeconfigStorageResource && cfg.KubeconfigStorage != user.KubeconfigStorageSecret {
+		return fmt.Errorf("invalid kubeconfig storage %q, must be resource or secret", cfg.KubeconfigStorage)
+	}
+	if cfg.TokenLifetime < 10*time.Minute {
+		return fmt.Errorf("token lifetime must be at least 10m")
+	}
+	if tokenAudiences != "" {
+		cfg.TokenAudiences = strings.Split(tokenAudiences, ",")
+	}
+	return nil
+}
+
+// loadSigner loads the client CA from the signer secret or files
+func loadSigner(coreClient corev1.CoreV1Interface) (*user.Signer, error) {
+	if signerSecret != "" {
+		secret, err := coreClient.Secrets(cfg.Namespace).Get(context.Background(), signerSecret, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return user.NewSigner(secret.Data[v1.TLSCertKey], secret.Data[v1.TLSPrivateKeyKey])
+	}
+
+	if signerCertFile == "" || signerKeyFile == "" {
+		return nil, fmt.Errorf("ca mode needs a signer secret or a signer cert and key file")
+	}
+	certPEM, err := ioutil.ReadFile(signerCertFile)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := ioutil.ReadFile(signerKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return user.NewSigner(certPEM, keyPEM)
+}
+
+func runWebhook(c *cli.Context) error {
+	logrus.Info("Starting klum webhook")
+	ctx := signals.SetupSignalContext()
+
+	switch webhookConfig.FailurePolicy {
+	case "Fail", "Ignore":
+	default:
+		return fmt.Errorf("invalid failure policy %q, must be Fail or Ignore", webhookConfig.FailurePolicy)
+	}
+
+	if err := parseConfig(); err != nil {
+		return err
+	}
+
+	restConfig, err := kubeconfig.GetNonInteractiveClientConfig(kubeConfig).ClientConfig()
+	if err != nil {
+		return err
+	}
+
+	core, err := core.NewFactoryFromConfig(restConfig)
+	if err != nil {
+		return err
+	}
+
+	klum, err := klum.NewFactoryFromConfig(restConfig)
+	if err != nil {
+		return err
+	}
+
+	rbac, err := rbac.NewFactoryFromConfig(restConfig)
+	if err != nil {
+		return err
+	}
+
+	validator := user.NewValidator(cfg,
+		klum.Klum().V1alpha1().User(),
+		rbac.Rbac().V1().ClusterRole(),
+		rbac.Rbac().V1().Role(),
+		core.Core().V1().Namespace(),
+		klum.Klum().V1alpha1().RoleTemplate(),
+		klum.Klum().V1alpha1().KlumPolicy())
+
+	if err := start.All(ctx, 2, klum, core, rbac); err != nil {
+		logrus.Fatalf("Error starting: %s", err.Error())
+	}
+
+	webhookConfig.Name = "klum"
+	webhookConfig.Namespace = cfg.Namespace
+	webhookConfig.ControllerUsername = webhook.ServiceAccountUsername(cfg.Namespace, controllerServiceAccount)
+	return webhook.Run(ctx, webhookConfig, restConfig, validator)
+}
+
+func runCredential(c *cli.Context) error {
+	if credentialServer == "" || credentialUser == "" || refreshToken == "" {
+		return fmt.Errorf("server, user and refresh token are required")
+	}
+
+	token, err := credential.Request(credentialServer, credentialUser, refreshToken, caFile)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(os.Stdout).Encode(credential.ExecCredential(token))
+}
+
+func runDecrypt(c *cli.Context) error {
+	if identityFile == "" {
+		return fmt.Errorf("identity is required")
+	}
+	identity, err := ioutil.ReadFile(identityFile)
+	if err != nil {
+		return err
+	}
+
+	var ciphertext []byte
+	if c.NArg() > 0 && c.Args().First() != "-" {
+		ciphertext, err = ioutil.ReadFile(c.Args().First())
+	} else {
+		ciphertext, err = ioutil.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return err
+	}
+
+	kubeconfig, err := encryption.Decrypt(ciphertext, identity, []byte(passphrase))
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(kubeconfig)
+	return err
+}
@@ -3,11 +3,26 @@ package v1alpha1
 import (
 	"github.com/rancher/wrangler/pkg/condition"
 	"github.com/rancher/wrangler/pkg/genericcondition"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var (
-	UserReadyCondition = condition.Cond("Ready")
+	UserReadyCondition             = condition.Cond("Ready")
+	UserExpiredCondition           = condition.Cond("Expired")
+	UserScheduleCondition          = condition.Cond("InSchedule")
+	UserRolesResolvedCondition     = condition.Cond("RolesResolved")
+	UserPolicyViolationCondition   = condition.Cond("PolicyViolation")
+	UserHomeNamespaceCondition     = condition.Cond("HomeNamespaceReady")
+	UserCredentialRevokedCondition = condition.Cond("CredentialRevoked")
+
+	AccessRequestApprovedCondition = condition.Cond("Approved")
+	AccessRequestExpiredCondition  = condition.Cond("Expired")
+
+	CredentialReadyCondition         = condition.Cond("Ready")
+	CredentialExpiredCondition       = condition.Cond("Expired")
+	CredentialRolesResolvedCondition = condition.Cond("RolesResolved")
 )
 
 // +genclient
@@ -25,16 +40,337 @@ type UserSpec struct {
 	Enabled      *bool           `json:"enabled,omitempty"`
 	ClusterRoles []string        `json:"clusterRoles,omitempty"`
 	Roles        []NamespaceRole `json:"roles,omitempty"`
+	// Rules are rendered into a ClusterRole owned by this user and bound cluster wide
+	Rules []rbacv1.PolicyRule `json:"rules,omitempty"`
+	// RoleTemplates are the names of RoleTemplates bound cluster wide
+	RoleTemplates []string `json:"roleTemplates,omitempty"`
+	// ExpiresAt is the time after which the user is disabled
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// Schedule limits the roles of the user to recurring windows of time
+	Schedule *Schedule `json:"schedule,omitempty"`
+	// HomeNamespace provisions a namespace the user is admin of
+	HomeNamespace *HomeNamespace `json:"homeNamespace,omitempty"`
+	// Groups are the Kubernetes groups the user is a member of when it
+	// authenticates with a client certificate. Groups starting with system:
+	// are reserved.
+	Groups []string `json:"groups,omitempty"`
+	// CertificateRequest is a PEM encoded certificate signing request for a key
+	// of the user. Its certificate is put in the Kubeconfig without a key, the
+	// key never leaves the machine of the user.
+	CertificateRequest string `json:"certificateRequest,omitempty"`
+	// CredentialGeneration is incremented to revoke the credential of the user
+	// and issue a new Kubeconfig, the roles of the user are kept. Client
+	// certificates can't be revoked, the previous one stays valid until it
+	// expires and rotating one requires a certificateRequest for a new key.
+	CredentialGeneration int64 `json:"credentialGeneration,omitempty"`
+	// OIDC is the identity of the user at the OIDC issuer the cluster trusts
+	OIDC *OIDCIdentity `json:"oidc,omitempty"`
+	// DefaultNamespace is the namespace of the current context of the
+	// Kubeconfig, by default it is the cluster for users with cluster wide
+	// roles and their first namespace otherwise
+	DefaultNamespace string `json:"defaultNamespace,omitempty"`
+	// PublicKey is an age X25519 recipient, an ssh-ed25519 public key or an
+	// armored OpenPGP public key. The Kubeconfigs of the user are encrypted to
+	// it and decrypted with "klum kubeconfig decrypt".
+	PublicKey string `json:"publicKey,omitempty"`
+}
+
+type OIDCIdentity struct {
+	// Subject is the sub claim of the user, required unless the cluster
+	// authenticates users by their email
+	Subject string `json:"subject,omitempty"`
+	// Email is the email claim of the user, used instead of the subject when
+	// the cluster authenticates users by their email
+	Email string `json:"email,omitempty"`
+	// Groups are groups of the issuer the roles of the user are bound to as
+	// well, so that every member of them is granted the roles
+	Groups []string `json:"groups,omitempty"`
 }
 
 type UserStatus struct {
 	Conditions []genericcondition.GenericCondition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation of the user this status is for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Bindings are the role bindings generated for the user
+	Bindings []Binding `json:"bindings,omitempty"`
+	// ServiceAccountName is the name of the ServiceAccount of the user in the
+	// namespace of the controller
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// TokenSecretName is the name of the Secret holding the token of the user
+	TokenSecretName string `json:"tokenSecretName,omitempty"`
+	// KubeconfigName is the name of the Kubeconfig of the user
+	KubeconfigName string `json:"kubeconfigName,omitempty"`
+	// CredentialIssuedAt is when the credential in the Kubeconfig was issued
+	CredentialIssuedAt *metav1.Time `json:"credentialIssuedAt,omitempty"`
+	// CredentialGeneration is the credential generation of the user the
+	// credential was last rotated for
+	CredentialGeneration int64 `json:"credentialGeneration,omitempty"`
+	// LastRotatedAt is when the credential of the user was last rotated
+	LastRotatedAt *metav1.Time `json:"lastRotatedAt,omitempty"`
+	// LastRotationReason is why the credential of the user was last rotated
+	LastRotationReason string `json:"lastRotationReason,omitempty"`
+	// ExpiresIn is the time remaining until the user expires
+	ExpiresIn string `json:"expiresIn,omitempty"`
+	// NextScheduleTransition is when the roles of the user will next be granted
+	// or revoked by its schedule
+	NextScheduleTransition *metav1.Time `json:"nextScheduleTransition,omitempty"`
+}
+
+type Binding struct {
+	// Kind is either ClusterRoleBinding or RoleBinding
+	Kind      string         `json:"kind,omitempty"`
+	Name      string         `json:"name,omitempty"`
+	Namespace string         `json:"namespace,omitempty"`
+	RoleRef   rbacv1.RoleRef `json:"roleRef,omitempty"`
+}
+
+type HomeNamespace struct {
+	// Name of the namespace, defaults to the name of the user with the home
+	// namespace prefix of the controller
+	Name string `json:"name,omitempty"`
+	// ResourceQuota overrides the default quota of the controller
+	ResourceQuota *v1.ResourceQuotaSpec `json:"resourceQuota,omitempty"`
+	// LimitRange overrides the default limits of the controller
+	LimitRange *v1.LimitRangeSpec `json:"limitRange,omitempty"`
+	// Isolated denies ingress traffic from other namespaces, defaults to true
+	Isolated *bool `json:"isolated,omitempty"`
+}
+
+type Schedule struct {
+	// Timezone is the IANA name of the time zone of the windows, such as
+	// "America/Phoenix". Defaults to UTC
+	Timezone string `json:"timezone,omitempty"`
+	// Windows are the times during which the roles of the user are granted
+	Windows []Window `json:"windows,omitempty"`
+}
+
+type Window struct {
+	// Days are the days of the week the window starts on, such as "Mon",
+	// "Tuesday" or a range like "Mon-Fri". Every day if empty
+	Days []string `json:"days,omitempty"`
+	// Start is the time of day the window opens in "15:04" format
+	Start string `json:"start,omitempty"`
+	// End is the time of day the window closes in "15:04" format. An end before
+	// the start closes the window the next day
+	End string `json:"end,omitempty"`
 }
 
 type NamespaceRole struct {
-	Namespace   string `json:"namespace,omitempty"`
-	ClusterRole string `json:"clusterRole,omitempty"`
-	Role        string `json:"role,omitempty"`
+	// Namespace is the name of the namespace, or a glob pattern such as "team-*"
+	// matching the names of many namespaces
+	Namespace string `json:"namespace,omitempty"`
+	// NamespaceSelector matches namespaces by label, if Namespace is also set the
+	// namespace must match both
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	ClusterRole       string                `json:"clusterRole,omitempty"`
+	Role              string                `json:"role,omitempty"`
+	// RoleTemplate is the name of a RoleTemplate to bind in the namespace
+	RoleTemplate string `json:"roleTemplate,omitempty"`
+	// Rules are rendered into a Role owned by this user and bound in Namespace
+	Rules []rbacv1.PolicyRule `json:"rules,omitempty"`
+	// ExpiresAt is the time after which this role is no longer granted
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type Group struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              GroupSpec `json:"spec,omitempty"`
+}
+
+type GroupSpec struct {
+	// Members are the names of the Users in this group
+	Members []string `json:"members,omitempty"`
+	// UserSelector adds all Users matching the selector to this group
+	UserSelector *metav1.LabelSelector `json:"userSelector,omitempty"`
+
+	// ClusterRoles, Roles, Rules and RoleTemplates are granted to every member of the group
+	ClusterRoles  []string            `json:"clusterRoles,omitempty"`
+	Roles         []NamespaceRole     `json:"roles,omitempty"`
+	Rules         []rbacv1.PolicyRule `json:"rules,omitempty"`
+	RoleTemplates []string            `json:"roleTemplates,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type AccessRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              AccessRequestSpec   `json:"spec,omitempty"`
+	Status            AccessRequestStatus `json:"status,omitempty"`
+}
+
+type AccessRequestSpec struct {
+	// User is the name of the User requesting access
+	User string `json:"user,omitempty"`
+	// ClusterRoles and Roles are granted to the user while the request is approved
+	ClusterRoles []string        `json:"clusterRoles,omitempty"`
+	Roles        []NamespaceRole `json:"roles,omitempty"`
+	// Justification is why the access is needed
+	Justification string `json:"justification,omitempty"`
+	// Duration is how long the access is granted for once approved, defaults to one hour
+	Duration metav1.Duration `json:"duration,omitempty"`
+}
+
+type AccessRequestStatus struct {
+	Conditions []genericcondition.GenericCondition `json:"conditions,omitempty"`
+	// State is one of Pending, Approved, Expired or Revoked
+	State string `json:"state,omitempty"`
+	// ApprovedBy is set by the approver through the status subresource, so
+	// only users allowed to update accessrequests/status can approve. It is the
+	// name of the User the approver authenticates as, or its username, and can
+	// not be the user requesting access. Clearing it revokes the request.
+	ApprovedBy string       `json:"approvedBy,omitempty"`
+	ApprovedAt *metav1.Time `json:"approvedAt,omitempty"`
+	ExpiresAt  *metav1.Time `json:"expiresAt,omitempty"`
+	// ApprovedGeneration is the generation of the request that was approved.
+	// Changing an approved request revokes it.
+	ApprovedGeneration int64 `json:"approvedGeneration,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Credential is an additional credential of a User with its own
+// ServiceAccount, bindings and Kubeconfig, which is deleted with it
+type Credential struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              CredentialSpec   `json:"spec,omitempty"`
+	Status            CredentialStatus `json:"status,omitempty"`
+}
+
+type CredentialSpec struct {
+	// User is the name of the User the credential is for
+	User string `json:"user,omitempty"`
+	// Description is what the credential is used for
+	Description string `json:"description,omitempty"`
+	// ClusterRoles and Roles are the roles of the user granted to the
+	// credential, all of them are granted when both are empty
+	ClusterRoles []string        `json:"clusterRoles,omitempty"`
+	Roles        []NamespaceRole `json:"roles,omitempty"`
+	// TTL is how long the credential is valid after it was created, it doesn't
+	// expire when empty
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+}
+
+type CredentialStatus struct {
+	Conditions []genericcondition.GenericCondition `json:"conditions,omitempty"`
+	// ExpiresAt is when the TTL of the credential has passed
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// ServiceAccountName is the name of the ServiceAccount of the credential in
+	// the namespace of the controller
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// KubeconfigName is the name of the Kubeconfig of the credential
+	KubeconfigName string `json:"kubeconfigName,omitempty"`
+	// Bindings are the role bindings generated for the credential
+	Bindings []Binding `json:"bindings,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type RoleTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              RoleTemplateSpec `json:"spec,omitempty"`
+}
+
+type RoleTemplateSpec struct {
+	// Rules are the permissions granted by the template
+	Rules []rbacv1.PolicyRule `json:"rules,omitempty"`
+	// Templates are the names of other RoleTemplates whose permissions are
+	// included in this template
+	Templates []string `json:"templates,omitempty"`
+	// Namespaced templates can only be granted in a namespace through roles
+	Namespaced bool `json:"namespaced,omitempty"`
+	// AggregateTo are the names of aggregated ClusterRoles, such as "edit", that
+	// the permissions of this template are added to. They have to be allowed
+	// by a KlumPolicy.
+	AggregateTo []string `json:"aggregateTo,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// KlumPolicy restricts the roles that may be granted to users. Every binding
+// has to satisfy all policies.
+type KlumPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              KlumPolicySpec `json:"spec,omitempty"`
+}
+
+type KlumPolicySpec struct {
+	// AllowedClusterRoles are glob patterns of the ClusterRoles that may be
+	// granted, cluster wide or in a namespace. If empty all are allowed.
+	AllowedClusterRoles []string `json:"allowedClusterRoles,omitempty"`
+	// DeniedClusterRoles are glob patterns of the ClusterRoles that may not be granted
+	DeniedClusterRoles []string `json:"deniedClusterRoles,omitempty"`
+	// AllowedRoles are glob patterns of the Roles that may be granted. If
+	// empty all are allowed.
+	AllowedRoles []string `json:"allowedRoles,omitempty"`
+	// DeniedRoles are glob patterns of the Roles that may not be granted
+	DeniedRoles []string `json:"deniedRoles,omitempty"`
+	// Namespaces further restrict the roles that may be granted in namespaces
+	// matching a pattern
+	Namespaces []NamespacePolicy `json:"namespaces,omitempty"`
+	// CeilingClusterRole is a ClusterRole whose rules must include the rules
+	// of every granted role, including inline rules and RoleTemplates
+	// aggregated to other ClusterRoles
+	CeilingClusterRole string `json:"ceilingClusterRole,omitempty"`
+	// AllowedAggregateTo are glob patterns of the ClusterRoles RoleTemplates
+	// may add their permissions to. RoleTemplates can only aggregate to
+	// ClusterRoles allowed by a policy.
+	AllowedAggregateTo []string `json:"allowedAggregateTo,omitempty"`
+	// AllowedGroups are glob patterns of the groups users may be members of
+	// in their client certificates, and of the OIDC groups the roles of users
+	// may be bound to. Groups are denied unless a policy allows them, and the system:
+	// groups never are.
+	AllowedGroups []string `json:"allowedGroups,omitempty"`
+}
+
+type NamespacePolicy struct {
+	// Namespace is a glob pattern of namespace names
+	Namespace string `json:"namespace,omitempty"`
+	// AllowedClusterRoles are glob patterns of the ClusterRoles that may be
+	// granted in the namespace
+	AllowedClusterRoles []string `json:"allowedClusterRoles,omitempty"`
+	// AllowedRoles are glob patterns of the Roles that may be granted in the namespace
+	AllowedRoles []string `json:"allowedRoles,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// UserPermissions are the effective permissions of the User with the same name
+type UserPermissions struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              UserPermissionsSpec `json:"spec,omitempty"`
+}
+
+type UserPermissionsSpec struct {
+	// ClusterRules are granted by ClusterRoleBindings, they apply to cluster
+	// scoped resources and to every namespace
+	ClusterRules []rbacv1.PolicyRule `json:"clusterRules,omitempty"`
+	// Namespaces are the rules granted by RoleBindings in each namespace
+	Namespaces []NamespacePermissions `json:"namespaces,omitempty"`
+}
+
+type NamespacePermissions struct {
+	Namespace string              `json:"namespace,omitempty"`
+	Rules     []rbacv1.PolicyRule `json:"rules,omitempty"`
 }
 
 // +genclient
@@ -45,7 +381,22 @@ type Kubeconfig struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec KubeconfigSpec `json:"spec,omitempty"`
+	Spec   KubeconfigSpec   `json:"spec,omitempty"`
+	Status KubeconfigStatus `json:"status,omitempty"`
+}
+
+type KubeconfigStatus struct {
+	// IssuedAt is when the credential in the Kubeconfig was issued
+	IssuedAt *metav1.Time `json:"issuedAt,omitempty"`
+	// ExpiresAt is when the credential in the Kubeconfig expires, the
+	// Kubeconfig is re-issued before then
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// SerialNumber is the hex encoded serial number of the client certificate
+	// in the Kubeconfig, if it was signed by klum
+	SerialNumber string `json:"serialNumber,omitempty"`
+	// SecretRef is the Secret holding the Kubeconfig with its credential when
+	// Kubeconfigs are stored in Secrets, the spec then holds no credential
+	SecretRef *v1.SecretReference `json:"secretRef,omitempty"`
 }
 
 type KubeconfigSpec struct {
@@ -56,6 +407,9 @@ type KubeconfigSpec struct {
 	Contexts []NamedContext `json:"contexts"`
 	// CurrentContext is the name of the context that you would like to use by default
 	CurrentContext string `json:"current-context"`
+	// EncryptedConfig is the Kubeconfig encrypted to the public key of the user,
+	// the auth infos then hold no credential
+	EncryptedConfig string `json:"encryptedConfig,omitempty"`
 }
 
 // NamedCluster relates nicknames to cluster information
@@ -88,6 +442,50 @@ type AuthInfo struct {
 	// Token is the bearer token for authentication to the kubernetes cluster.
 	// +optional
 	Token string `json:"token,omitempty"`
+	// ClientCertificateData contains PEM-encoded data from a client cert file for TLS.
+	// +optional
+	ClientCertificateData string `json:"client-certificate-data,omitempty"`
+	// ClientKeyData contains PEM-encoded data from a client key file for TLS.
+	// +optional
+	ClientKeyData string `json:"client-key-data,omitempty"`
+	// AuthProvider specifies a custom authentication plugin for the kubernetes cluster.
+	// +optional
+	AuthProvider *AuthProviderConfig `json:"auth-provider,omitempty"`
+	// Exec specifies a command to run to get credentials
+	// +optional
+	Exec *ExecConfig `json:"exec,omitempty"`
+}
+
+// AuthProviderConfig holds the configuration for a specified auth provider.
+type AuthProviderConfig struct {
+	Name string `json:"name"`
+	// +optional
+	Config map[string]string `json:"config,omitempty"`
+}
+
+// ExecConfig specifies a command to provide client credentials. The command is exec'd
+// and outputs structured stdout holding credentials.
+type ExecConfig struct {
+	// Command to execute.
+	Command string `json:"command"`
+	// Arguments to pass to the command when executing it.
+	// +optional
+	Args []string `json:"args,omitempty"`
+	// Env defines additional environment variables to expose to the process.
+	// +optional
+	Env []ExecEnvVar `json:"env,omitempty"`
+	// Preferred input version of the ExecInfo.
+	APIVersion string `json:"apiVersion,omitempty"`
+	// InstallHint is printed when the command can't be found.
+	// +optional
+	InstallHint string `json:"installHint,omitempty"`
+}
+
+// ExecEnvVar is used for setting environment variables when executing an exec-based
+// credential plugin.
+type ExecEnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
 }
 
 // Context is a tuple of references to a cluster (how do I communicate with a kubernetes cluster), a user (how do I identify myself), and a namespace (what subset of resources do I want to work with)
@@ -96,6 +494,9 @@ type Context struct {
 	Cluster string `json:"cluster"`
 	// AuthInfo is the name of the authInfo for this context
 	AuthInfo string `json:"user"`
+	// Namespace is the default namespace to use on unspecified requests
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
 }
 
 // NamedContext relates nicknames to context information
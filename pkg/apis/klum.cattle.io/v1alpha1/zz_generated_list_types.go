@@ -57,3 +57,105 @@ func NewKubeconfig(namespace, name string, obj Kubeconfig) *Kubeconfig {
 	obj.Namespace = namespace
 	return &obj
 }
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// GroupList is a list of Group resources
+type GroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []Group `json:"items"`
+}
+
+func NewGroup(namespace, name string, obj Group) *Group {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("Group").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AccessRequestList is a list of AccessRequest resources
+type AccessRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []AccessRequest `json:"items"`
+}
+
+func NewAccessRequest(namespace, name string, obj AccessRequest) *AccessRequest {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("AccessRequest").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RoleTemplateList is a list of RoleTemplate resources
+type RoleTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []RoleTemplate `json:"items"`
+}
+
+func NewRoleTemplate(namespace, name string, obj RoleTemplate) *RoleTemplate {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("RoleTemplate").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// UserPermissionsList is a list of UserPermissions resources
+type UserPermissionsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []UserPermissions `json:"items"`
+}
+
+func NewUserPermissions(namespace, name string, obj UserPermissions) *UserPermissions {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("UserPermissions").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// KlumPolicyList is a list of KlumPolicy resources
+type KlumPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []KlumPolicy `json:"items"`
+}
+
+func NewKlumPolicy(namespace, name string, obj KlumPolicy) *KlumPolicy {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("KlumPolicy").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CredentialList is a list of Credential resources
+type CredentialList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []Credential `json:"items"`
+}
+
+func NewCredential(namespace, name string, obj Credential) *Credential {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("Credential").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
@@ -28,8 +28,14 @@ import (
 )
 
 var (
-	KubeconfigResourceName = "kubeconfigs"
-	UserResourceName       = "users"
+	AccessRequestResourceName   = "accessrequests"
+	CredentialResourceName      = "credentials"
+	GroupResourceName           = "groups"
+	KlumPolicyResourceName      = "klumpolicies"
+	KubeconfigResourceName      = "kubeconfigs"
+	RoleTemplateResourceName    = "roletemplates"
+	UserResourceName            = "users"
+	UserPermissionsResourceName = "userpermissionses"
 )
 
 // SchemeGroupVersion is group version used to register these objects
@@ -53,10 +59,22 @@ var (
 // Adds the list of known types to Scheme.
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
+		&AccessRequest{},
+		&AccessRequestList{},
+		&Credential{},
+		&CredentialList{},
+		&Group{},
+		&GroupList{},
+		&KlumPolicy{},
+		&KlumPolicyList{},
 		&Kubeconfig{},
 		&KubeconfigList{},
+		&RoleTemplate{},
+		&RoleTemplateList{},
 		&User{},
 		&UserList{},
+		&UserPermissions{},
+		&UserPermissionsList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -18,6 +18,12 @@ func main() {
 				Types: []interface{}{
 					v1alpha1.User{},
 					v1alpha1.Kubeconfig{},
+					v1alpha1.Group{},
+					v1alpha1.AccessRequest{},
+					v1alpha1.RoleTemplate{},
+					v1alpha1.UserPermissions{},
+					v1alpha1.KlumPolicy{},
+					v1alpha1.Credential{},
 				},
 				GenerateTypes: true,
 			},
@@ -0,0 +1,130 @@
+package user
+
+import (
+	"fmt"
+	"time"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	"github.com/rancher/wrangler/pkg/condition"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	accessRequestByUser = "klum.cattle.io/access-request-by-user"
+
+	defaultAccessDuration = time.Hour
+
+	statePending  = "Pending"
+	stateApproved = "Approved"
+	stateExpired  = "Expired"
+	stateRevoked  = "Revoked"
+)
+
+func indexAccessRequestByUser(obj *klum.AccessRequest) ([]string, error) {
+	return []string{obj.Spec.User}, nil
+}
+
+// OnAccessRequestChange approves requests once a different user is set as the
+// approver in their status and expires them when their duration has passed.
+func (h *handler) OnAccessRequestChange(req *klum.AccessRequest, status klum.AccessRequestStatus) (klum.AccessRequestStatus, error) {
+	now := time.Now()
+
+	switch {
+	case status.State == stateExpired || status.State == stateRevoked:
+		return status, nil
+	case status.ApprovedAt != nil && status.ApprovedGeneration != req.Generation:
+		status.State = stateRevoked
+		status = setAccessRequestCondition(status, klum.AccessRequestApprovedCondition, false,
+			"request was changed after it was approved")
+		return status, nil
+	case status.ApprovedAt != nil && status.ApprovedBy == "":
+		status.State = stateRevoked
+		status = setAccessRequestCondition(status, klum.AccessRequestApprovedCondition, false,
+			"approval was withdrawn")
+		return status, nil
+	case status.ApprovedAt != nil:
+		if expired(status.ExpiresAt, now) {
+			status.State = stateExpired
+			return setAccessRequestCondition(status, klum.AccessRequestExpiredCondition, true, ""), nil
+		}
+		h.accessRequestController.EnqueueAfter(req.Name, status.ExpiresAt.Sub(now)+time.Second)
+		return status, nil
+	}
+
+	status.State = statePending
+	if status.ApprovedBy == "" {
+		return setAccessRequestCondition(status, klum.AccessRequestApprovedCondition, false,
+			"waiting for approval"), nil
+	}
+
+	if err := validateApprover(req.Spec.User, status.ApprovedBy); err != nil {
+		return setAccessRequestCondition(status, klum.AccessRequestApprovedCondition, false,
+			err.Error()), nil
+	}
+
+	duration := req.Spec.Duration.Duration
+	if duration <= 0 {
+		duration = defaultAccessDuration
+	}
+
+	status.State = stateApproved
+	status.ApprovedAt = &metav1.Time{Time: now}
+	status.ExpiresAt = &metav1.Time{Time: now.Add(duration)}
+	status.ApprovedGeneration = req.Generation
+	status = setAccessRequestCondition(status, klum.AccessRequestApprovedCondition, true,
+		fmt.Sprintf("approved by %s", status.ApprovedBy))
+	status = setAccessRequestCondition(status, klum.AccessRequestExpiredCondition, false, "")
+	h.accessRequestController.EnqueueAfter(req.Name, duration+time.Second)
+
+	return status, nil
+}
+
+// validateApprover rejects users approving their own requests. Only users
+// allowed to update the status of the request can set the approver, the
+// webhook additionally checks that they set themselves.
+func validateApprover(user, approvedBy string) error {
+	if approvedBy == user {
+		return fmt.Errorf("user %s can not approve its own request", user)
+	}
+	return nil
+}
+
+// activeAccessRequests returns the approved and unexpired requests of the user
+func (h *handler) activeAccessRequests(user string, now time.Time) ([]*klum.AccessRequest, error) {
+	reqs, err := h.accessRequests.GetByIndex(accessRequestByUser, user)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*klum.AccessRequest
+	for _, req := range reqs {
+		if req.Status.ApprovedAt == nil ||
+			req.Status.ApprovedBy == "" ||
+			req.Status.ApprovedGeneration != req.Generation ||
+			req.Status.State != stateApproved ||
+			expired(req.Status.ExpiresAt, now) {
+			continue
+		}
+		result = append(result, req)
+	}
+
+	return result, nil
+}
+
+// resolveAccessRequest enqueues the user of a request so its roles follow the
+// request being approved, expiring or deleted.
+func (h *handler) resolveAccessRequest(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	if req, ok := obj.(*klum.AccessRequest); ok {
+		return []relatedresource.Key{relatedresource.NewKey("", req.Spec.User)}, nil
+	}
+	return nil, nil
+}
+
+func setAccessRequestCondition(status klum.AccessRequestStatus, cond condition.Cond, value bool, message string) klum.AccessRequestStatus {
+	req := &klum.AccessRequest{Status: status}
+	cond.SetStatusBool(req, value)
+	cond.Message(req, message)
+	return req.Status
+}
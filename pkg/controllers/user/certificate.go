@@ -0,0 +1,392 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	name2 "github.com/rancher/wrangler/pkg/name"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// CredentialModeCertificate puts client certificates signed through the
+	// CertificateSigningRequest API in Kubeconfigs
+	CredentialModeCertificate = "certificate"
+
+	csrSignerName = "kubernetes.io/kube-apiserver-client"
+	// csrPollInterval is how often a pending CertificateSigningRequest is checked
+	csrPollInterval = 2 * time.Second
+	// csrRetryInterval is how long to wait after a request was denied or failed
+	csrRetryInterval = time.Minute
+
+	// reservedGroupPrefix is the prefix of the groups Kubernetes reserves for
+	// itself, such as system:masters
+	reservedGroupPrefix = "system:"
+)
+
+var csrResource = schema.GroupVersionResource{
+	Group:    "certificates.k8s.io",
+	Version:  "v1",
+	Resource: "certificatesigningrequests",
+}
+
+// pendingCertificate is a CertificateSigningRequest waiting to be signed and
+// the key it is for. The key only lives in memory, a request pending when the
+// controller restarts is replaced.
+type pendingCertificate struct {
+	csrName string
+	groups  []string
+	// request is the DER encoded request of the user, keyPEM is empty then
+	request []byte
+	keyPEM  []byte
+}
+
+// ensureCertificate issues a Kubeconfig with a client certificate for the user
+// when it has none, its certificate is due for renewal or its groups or
+// certificate request changed. The certificate is requested, approved and then
+// polled for by requeueing the user until it is signed.
+func (h *handler) ensureCertificate(user *klum.User) error {
+	now := time.Now()
+	groups := sortedGroups(user.Spec.Groups)
+
+	if ok, err := h.checkGroups(user, groups); err != nil || !ok {
+		return err
+	}
+
+	request, err := certificateRequest(user, groups)
+	if err != nil {
+		h.recorder.Event(user, v1.EventTypeWarning, "InvalidCertificateRequest", err.Error())
+		return nil
+	}
+
+	cert, err := h.clientCertificate(user.Name, groups, request)
+	if err != nil {
+		return err
+	}
+	if cert != nil {
+		if renew := renewAt(cert.NotBefore, cert.NotAfter); now.Before(renew) {
+			h.userController.EnqueueAfter(user.Name, renew.Sub(now))
+			return nil
+		}
+	}
+
+	h.pendingLock.Lock()
+	defer h.pendingLock.Unlock()
+
+	pending, ok := h.pendingCertificates[user.Name]
+	if ok && (!reflect.DeepEqual(pending.groups, groups) || !bytes.Equal(pending.request, requestDER(request))) {
+		delete(h.pendingCertificates, user.Name)
+		if err := h.deleteCSR(pending.csrName); err != nil {
+			return err
+		}
+		ok = false
+	}
+	if !ok {
+		return h.requestCertificate(user, groups, request)
+	}
+
+	csr, err := h.csrs.Get(context.TODO(), pending.csrName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		delete(h.pendingCertificates, user.Name)
+		h.userController.EnqueueAfter(user.Name, csrPollInterval)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if reason := csrFailure(csr); reason != "" {
+		h.recorder.Event(user, v1.EventTypeWarning, "CertificateFailed", reason)
+		delete(h.pendingCertificates, user.Name)
+		h.userController.EnqueueAfter(user.Name, csrRetryInterval)
+		return h.deleteCSR(pending.csrName)
+	}
+
+	encoded, _, _ := unstructured.NestedString(csr.Object, "status", "certificate")
+	if encoded == "" {
+		h.userController.EnqueueAfter(user.Name, csrPollInterval)
+		return nil
+	}
+
+	certPEM, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+	cert, err = parseCertificate(certPEM)
+	if err != nil {
+		return err
+	}
+
+	namespaces, currentNamespace, err := h.userNamespaces(user)
+	if err != nil {
+		return err
+	}
+	ca, err := h.clusterCA()
+	if err != nil {
+		return err
+	}
+
+	err = h.applyKubeconfig(user, h.kubeconfig(user.Name, ca, klum.AuthInfo{
+		ClientCertificateData: base64.StdEncoding.EncodeToString(certPEM),
+		ClientKeyData:         base64.StdEncoding.EncodeToString(pending.keyPEM),
+	}, namespaces, currentNamespace), klum.KubeconfigStatus{
+		IssuedAt:  &metav1.Time{Time: now},
+		ExpiresAt: &metav1.Time{Time: cert.NotAfter},
+	})
+	if err != nil {
+		return err
+	}
+
+	delete(h.pendingCertificates, user.Name)
+	h.userController.EnqueueAfter(user.Name, renewAt(cert.NotBefore, cert.NotAfter).Sub(now))
+	return h.deleteCSR(pending.csrName)
+}
+
+// checkGroups returns whether the user may get a client certificate with the
+// groups. Otherwise the Kubeconfig is deleted, its certificate may have been
+// issued before a policy restricted the groups.
+func (h *handler) checkGroups(user *klum.User, groups []string) (bool, error) {
+	violations, err := h.groupViolations(groups)
+	if err != nil || len(violations) == 0 {
+		return err == nil, err
+	}
+	h.recorder.Event(user, v1.EventTypeWarning, "InvalidGroups", strings.Join(violations, "; "))
+	return false, h.deleteKubeconfig(user.Name)
+}
+
+// requestCertificate creates and approves a CertificateSigningRequest for the
+// certificate request of the user or, if it has none, a new key
+func (h *handler) requestCertificate(user *klum.User, groups []string, userRequest *x509.CertificateRequest) error {
+	request, keyPEM := requestDER(userRequest), []byte(nil)
+	if request == nil {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return err
+		}
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return err
+		}
+		request, err = x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject: pkix.Name{
+				CommonName:   user.Name,
+				Organization: groups,
+			},
+		}, key)
+		if err != nil {
+			return err
+		}
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	}
+
+	csr := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "certificates.k8s.io/v1",
+			"kind":       "CertificateSigningRequest",
+			"metadata": map[string]interface{}{
+				"generateName": name2.SafeConcatName("klum", user.Name) + "-",
+				"annotations": map[string]interface{}{
+					"klum.cattle.io/user": user.Name,
+				},
+			},
+			"spec": map[string]interface{}{
+				"request":           base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: request})),
+				"signerName":        csrSignerName,
+				"usages":            []interface{}{"digital signature", "key encipherment", "client auth"},
+				"expirationSeconds": int64(h.cfg.CertificateLifetime / time.Second),
+			},
+		},
+	}
+	// the request is deleted with the user
+	csr.SetOwnerReferences([]metav1.OwnerReference{
+		{
+			APIVersion: klum.SchemeGroupVersion.String(),
+			Kind:       "User",
+			Name:       user.Name,
+			UID:        user.UID,
+		},
+	})
+
+	csr, err := h.csrs.Create(context.TODO(), csr, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+
+	err = unstructured.SetNestedSlice(csr.Object, []interface{}{
+		map[string]interface{}{
+			"type":    "Approved",
+			"status":  "True",
+			"reason":  "KlumApproved",
+			"message": fmt.Sprintf("approved by klum for user %s", user.Name),
+		},
+	}, "status", "conditions")
+	if err != nil {
+		return err
+	}
+	if _, err := h.csrs.Update(context.TODO(), csr, metav1.UpdateOptions{}, "approval"); err != nil {
+		return err
+	}
+
+	h.pendingCertificates[user.Name] = pendingCertificate{
+		csrName: csr.GetName(),
+		groups:  groups,
+		request: requestDER(userRequest),
+		keyPEM:  keyPEM,
+	}
+	h.userController.EnqueueAfter(user.Name, csrPollInterval)
+	return nil
+}
+
+func (h *handler) deleteCSR(name string) error {
+	err := h.csrs.Delete(context.TODO(), name, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// deleteKubeconfig deletes the Kubeconfig of a user that isn't active
+func (h *handler) deleteKubeconfig(user string) error {
+	h.pendingLock.Lock()
+	delete(h.pendingCertificates, user)
+	h.pendingLock.Unlock()
+
+	if _, err := h.kubeconfigs.Get(user); errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	err := h.kubeconfigController.Delete(user, &metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// csrFailure returns why a CertificateSigningRequest was denied or failed
+func csrFailure(csr *unstructured.Unstructured) string {
+	conditions, _, _ := unstructured.NestedSlice(csr.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if t := condition["type"]; t == "Denied" || t == "Failed" {
+			return fmt.Sprintf("certificate signing request %s %s: %v", csr.GetName(), t, condition["message"])
+		}
+	}
+	return ""
+}
+
+// clientCertificate returns the client certificate in the Kubeconfig of the
+// user, or nil if there is none or it is not for the user, groups and
+// certificate request. Without a request the Kubeconfig has to hold the key,
+// which can't be checked once it is encrypted.
+func (h *handler) clientCertificate(user string, groups []string, request *x509.CertificateRequest) (*x509.Certificate, error) {
+	kubeconfig, spec, cert, err := h.currentCertificate(user)
+	if err != nil || cert == nil {
+		return nil, err
+	}
+	if cert.Subject.CommonName != user || !reflect.DeepEqual(sortedGroups(cert.Subject.Organization), groups) {
+		return nil, nil
+	}
+	if request != nil && !bytes.Equal(cert.RawSubjectPublicKeyInfo, request.RawSubjectPublicKeyInfo) ||
+		request == nil && spec.AuthInfos[0].AuthInfo.ClientKeyData == "" && !encrypted(kubeconfig) {
+		return nil, nil
+	}
+	return cert, nil
+}
+
+// currentCertificate returns the Kubeconfig of the user, its spec and the
+// client certificate in it, if there is one
+func (h *handler) currentCertificate(user string) (*klum.Kubeconfig, klum.KubeconfigSpec, *x509.Certificate, error) {
+	kubeconfig, err := h.kubeconfigs.Get(user)
+	if errors.IsNotFound(err) {
+		return nil, klum.KubeconfigSpec{}, nil, nil
+	} else if err != nil {
+		return nil, klum.KubeconfigSpec{}, nil, err
+	}
+
+	spec, err := h.kubeconfigSpec(kubeconfig)
+	if err != nil {
+		return nil, spec, nil, err
+	}
+	if len(spec.AuthInfos) == 0 {
+		return kubeconfig, spec, nil, nil
+	}
+	certPEM, err := base64.StdEncoding.DecodeString(spec.AuthInfos[0].AuthInfo.ClientCertificateData)
+	if err != nil {
+		return kubeconfig, spec, nil, nil
+	}
+	cert, err := parseCertificate(certPEM)
+	if err != nil {
+		return kubeconfig, spec, nil, nil
+	}
+	return kubeconfig, spec, cert, nil
+}
+
+// certificateRequest returns the certificate request of the user, nil if it
+// has none or an error if it isn't signed or not for the user and groups
+func certificateRequest(user *klum.User, groups []string) (*x509.CertificateRequest, error) {
+	if user.Spec.CertificateRequest == "" {
+		return nil, nil
+	}
+	block, _ := pem.Decode([]byte(user.Spec.CertificateRequest))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" && block.Type != "NEW CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("no PEM encoded certificate request found")
+	}
+	request, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if err := request.CheckSignature(); err != nil {
+		return nil, err
+	}
+	if request.Subject.CommonName != user.Name {
+		return nil, fmt.Errorf("common name of certificate request is %q, not %q", request.Subject.CommonName, user.Name)
+	}
+	if organizations := sortedGroups(request.Subject.Organization); !reflect.DeepEqual(organizations, groups) {
+		return nil, fmt.Errorf("organizations of certificate request are %v, not the groups %v", organizations, groups)
+	}
+	return request, nil
+}
+
+func requestDER(request *x509.CertificateRequest) []byte {
+	if request == nil {
+		return nil
+	}
+	return request.Raw
+}
+
+func parseCertificate(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func sortedGroups(groups []string) []string {
+	result := append([]string{}, groups...)
+	sort.Strings(result)
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
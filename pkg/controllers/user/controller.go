@@ -6,20 +6,30 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
 	"github.com/ibuildthecloud/klum/pkg/generated/controllers/klum.cattle.io/v1alpha1"
+	"github.com/ibuildthecloud/klum/pkg/permissions"
 	v1controller "github.com/rancher/wrangler-api/pkg/generated/controllers/core/v1"
 	rbaccontroller "github.com/rancher/wrangler-api/pkg/generated/controllers/rbac/v1"
 	"github.com/rancher/wrangler/pkg/apply"
+	"github.com/rancher/wrangler/pkg/condition"
 	"github.com/rancher/wrangler/pkg/generic"
+	"github.com/rancher/wrangler/pkg/genericcondition"
 	name2 "github.com/rancher/wrangler/pkg/name"
+	"github.com/rancher/wrangler/pkg/relatedresource"
 	v1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 )
 
 type Config struct {
@@ -28,28 +38,102 @@ type Config struct {
 	Server             string
 	CA                 string
 	DefaultClusterRole string
+
+	HomeNamespaces      bool
+	HomeNamespacePrefix string
+	HomeNamespaceQuota  v1.ResourceList
+	HomeNamespaceLimits v1.ResourceList
+
+	// CredentialMode is how the credentials in Kubeconfigs are issued, one of
+	// CredentialModeSecret, CredentialModeTokenRequest, CredentialModeCertificate,
+	// CredentialModeCA, CredentialModeExec or CredentialModeOIDC
+	CredentialMode string
+	// TokenLifetime and TokenAudiences are requested for tokens issued by the
+	// TokenRequest API
+	TokenLifetime  time.Duration
+	TokenAudiences []string
+	// TokenSecretsCreated is whether the cluster creates a token Secret for
+	// every ServiceAccount, otherwise the controller creates them
+	TokenSecretsCreated bool
+	// CertificateLifetime is requested for client certificates
+	CertificateLifetime time.Duration
+	// Signer signs client certificates in CredentialModeCA
+	Signer *Signer
+	// CredentialServer is the external URL of the credential server the exec
+	// plugin fetches tokens from in CredentialModeExec
+	CredentialServer string
+	// OIDC is the issuer users authenticate with in CredentialModeOIDC
+	OIDC OIDCConfig
+	// KubeconfigStorage is where Kubeconfigs with their credentials are
+	// stored, KubeconfigStorageResource or KubeconfigStorageSecret
+	KubeconfigStorage string
+	// HomeNamespaceSecrets stores the Secrets of KubeconfigStorageSecret in
+	// the home namespaces of users, so that they can read them
+	HomeNamespaceSecrets bool
+
+	// EffectivePermissions enables a UserPermissions object per user listing
+	// the rules the user is granted
+	EffectivePermissions bool
 }
 
 func Register(ctx context.Context,
 	cfg Config,
 	apply apply.Apply,
+	recorder record.EventRecorder,
+	coreClient corev1.CoreV1Interface,
+	dynamicClient dynamic.Interface,
 	serviceAccount v1controller.ServiceAccountController,
+	clusterRole rbaccontroller.ClusterRoleController,
 	crb rbaccontroller.ClusterRoleBindingController,
+	role rbaccontroller.RoleController,
 	rb rbaccontroller.RoleBindingController,
 	secrets v1controller.SecretController,
+	namespace v1controller.NamespaceController,
 	kconfig v1alpha1.KubeconfigController,
+	group v1alpha1.GroupController,
+	accessRequest v1alpha1.AccessRequestController,
+	roleTemplate v1alpha1.RoleTemplateController,
+	policy v1alpha1.KlumPolicyController,
+	userPermissions v1alpha1.UserPermissionsController,
+	credential v1alpha1.CredentialController,
 	user v1alpha1.UserController) {
 
 	h := &handler{
 		cfg:             cfg,
-		apply:           apply.WithCacheTypes(kconfig),
+		apply:           apply.WithCacheTypes(kconfig, clusterRole, secrets),
+		recorder:        recorder,
+		coreClient:      coreClient,
+		csrs:            dynamicClient.Resource(csrResource),
 		serviceAccounts: serviceAccount.Cache(),
+		namespaces:      namespace.Cache(),
+		secrets:         secrets.Cache(),
+		kubeconfigs:     kconfig.Cache(),
+		clusterRoles:    clusterRole.Cache(),
+		roles:           role.Cache(),
+		groups:          group.Cache(),
+		accessRequests:  accessRequest.Cache(),
+		roleTemplates:   roleTemplate.Cache(),
+		permissions:     permissions.NewResolver(clusterRole.Cache(), role.Cache()),
+		policies:        policy.Cache(),
+		users:           user.Cache(),
+		userController:  user,
+		credentials:     credential.Cache(),
+
+		accessRequestController: accessRequest,
+		kubeconfigController:    kconfig,
+		credentialController:    credential,
+		secretController:        secrets,
+
+		pendingCertificates: map[string]pendingCertificate{},
 	}
 
+	accessRequest.Cache().AddIndexer(accessRequestByUser, indexAccessRequestByUser)
+	credential.Cache().AddIndexer(credentialByUser, indexCredentialByUser)
+
 	v1alpha1.RegisterUserGeneratingHandler(ctx,
 		user,
-		apply.WithCacheTypes(serviceAccount,
-			crb, rb),
+		apply.WithCacheTypes(serviceAccount, secrets,
+			clusterRole, crb, role, rb, namespace, userPermissions),
 		"",
 		"klum-user",
 		h.OnUserChange,
@@ -57,71 +141,282 @@ func Register(ctx context.Context,
 			AllowClusterScoped: true,
 		})
 
+	v1alpha1.RegisterCredentialGeneratingHandler(ctx,
+		credential,
+		apply.WithCacheTypes(serviceAccount, secrets,
+			clusterRole, crb, role, rb),
+		"",
+		"klum-credential",
+		h.OnCredentialChange,
+		&generic.GeneratingHandlerOptions{
+			AllowClusterScoped: true,
+		})
+
+	v1alpha1.RegisterAccessRequestStatusHandler(ctx,
+		accessRequest,
+		"",
+		"klum-access-request",
+		h.OnAccessRequestChange)
+
+	roleTemplate.OnChange(ctx, "klum-role-template", h.OnRoleTemplateChange)
 	secrets.OnChange(ctx, "klum-secret", h.OnSecretChange)
+	relatedresource.WatchClusterScoped(ctx, "klum-group", h.resolveGroup, user, group)
+	relatedresource.WatchClusterScoped(ctx, "klum-namespace", h.resolveNamespace, user, namespace)
+	relatedresource.WatchClusterScoped(ctx, "klum-access-request", h.resolveAccessRequest, user, accessRequest)
+	relatedresource.WatchClusterScoped(ctx, "klum-role-template", h.resolveRoleTemplate, user, roleTemplate)
+	relatedresource.WatchClusterScoped(ctx, "klum-policy", h.resolvePolicy, user, policy)
+	relatedresource.WatchClusterScoped(ctx, "klum-aggregating-template", h.resolveAggregatingTemplates, roleTemplate, policy, clusterRole, roleTemplate)
+	relatedresource.WatchClusterScoped(ctx, "klum-role", h.resolveRole, user, clusterRole, role)
+	relatedresource.WatchClusterScoped(ctx, "klum-kubeconfig", resolveKubeconfig, user, kconfig)
+	relatedresource.WatchClusterScoped(ctx, "klum-service-account", resolveServiceAccount, user, serviceAccount)
+	relatedresource.WatchClusterScoped(ctx, "klum-refresh-secret", resolveRefreshSecret, user, secrets)
+	relatedresource.WatchClusterScoped(ctx, "klum-kubeconfig-secret", resolveKubeconfigSecret, user, secrets)
+	relatedresource.WatchClusterScoped(ctx, "klum-credential-user", h.resolveCredentialUser, credential, user)
+	relatedresource.WatchClusterScoped(ctx, "klum-credential-object", h.resolveCredentialObject, credential, serviceAccount, secrets, kconfig)
+	relatedresource.WatchClusterScoped(ctx, "klum-credential-role", h.resolveUnresolvedCredentials, credential, clusterRole, role, namespace, roleTemplate)
 }
 
 type handler struct {
 	cfg             Config
 	apply           apply.Apply
+	recorder        record.EventRecorder
+	coreClient      corev1.CoreV1Interface
+	csrs            dynamic.NamespaceableResourceInterface
 	serviceAccounts v1controller.ServiceAccountCache
+	namespaces      v1controller.NamespaceCache
+	secrets         v1controller.SecretCache
+	kubeconfigs     v1alpha1.KubeconfigCache
+	clusterRoles    rbaccontroller.ClusterRoleCache
+	roles           rbaccontroller.RoleCache
+	groups          v1alpha1.GroupCache
+	accessRequests  v1alpha1.AccessRequestCache
+	roleTemplates   v1alpha1.RoleTemplateCache
+	permissions     *permissions.Resolver
+	policies        v1alpha1.KlumPolicyCache
+	users           v1alpha1.UserCache
+	userController  v1alpha1.UserController
+	credentials     v1alpha1.CredentialCache
+
+	accessRequestController v1alpha1.AccessRequestController
+	kubeconfigController    v1alpha1.KubeconfigController
+	credentialController    v1alpha1.CredentialController
+	secretController        v1controller.SecretController
+
+	pendingLock         sync.Mutex
+	pendingCertificates map[string]pendingCertificate
 }
 
 func (h *handler) OnUserChange(user *klum.User, status klum.UserStatus) ([]runtime.Object, klum.UserStatus, error) {
+	// nothing is generated for a user whose objects would collide with the
+	// ones of a credential
+	if reservedUserName(user.Name) {
+		return nil, setCondition(status, klum.UserReadyCondition, false,
+			fmt.Sprintf("names starting with %s- are reserved for credentials", credentialPrefix)), nil
+	}
+
+	objs, status, active, err := h.userObjects(user, status)
+	if err != nil {
+		return nil, status, err
+	}
+
+	status, rotated, err := h.rotateCredential(user, status)
+	if err != nil {
+		return nil, status, err
+	}
+	// a rotated credential is issued again once its deletion is observed
+	if !rotated {
+		if err := h.ensureCredential(user, active); err != nil {
+			return nil, status, err
+		}
+		if active {
+			if err := h.syncKubeconfig(user); err != nil {
+				return nil, status, err
+			}
+		}
+	}
+
+	status.ObservedGeneration = user.Generation
+	status.Bindings = bindings(objs)
+	status, err = h.setCredentialStatus(user, status, active)
+	if err != nil || !h.cfg.EffectivePermissions {
+		return objs, status, err
+	}
+
+	report, err := h.getPermissions(user, objs)
+	if err != nil {
+		return nil, status, err
+	}
+	return append(objs, report), status, nil
+}
+
+// userObjects returns the ServiceAccount, bindings and home namespace of the
+// user and whether the user is active, that is enabled and not expired
+func (h *handler) userObjects(user *klum.User, status klum.UserStatus) ([]runtime.Object, klum.UserStatus, bool, error) {
+	// the home namespace is kept while the user is disabled so nothing in it is lost
+	home := h.getHomeNamespace(user)
+	status, homeReady := h.setHomeNamespaceCondition(user, status)
+
 	if user.Spec.Enabled != nil && !*user.Spec.Enabled {
 		status = setReady(status, false)
-		return nil, status, nil
+		return home, status, false, nil
+	}
+
+	now := time.Now()
+	status, active := setExpiration(user, status, now)
+	if !active {
+		return home, setReady(status, false), false, nil
+	}
+
+	spec, err := h.effectiveSpec(user)
+	if err != nil {
+		return nil, status, false, err
+	}
+	h.enqueueExpiration(user, spec, now)
+
+	status, inSchedule := h.checkSchedule(user, status, now)
+
+	objs, err := h.getServiceAccount(user.Name)
+	if err != nil {
+		return nil, status, false, err
+	}
+	objs = append(objs, home...)
+
+	ready := homeReady
+	if inSchedule {
+		roles, missing, err := h.getRoles(user.Name, spec, h.subjects(user.Name, spec))
+		if err != nil {
+			return nil, status, false, err
+		}
+		roles = append(roles, h.getHomeBinding(user)...)
+
+		roles, violations, err := h.enforcePolicies(roles)
+		if err != nil {
+			return nil, status, false, err
+		}
+		// nothing is bound to an OIDC identity without its username or with
+		// groups the user may not be bound to
+		oidcViolations, err := h.oidcViolations(spec)
+		if err != nil {
+			return nil, status, false, err
+		}
+		if len(oidcViolations) > 0 {
+			roles = nil
+			violations = append(violations, oidcViolations...)
+		}
+		objs = append(objs, roles...)
+
+		ready = ready && len(missing) == 0 && len(violations) == 0
+		status = setCondition(status, klum.UserRolesResolvedCondition, len(missing) == 0, strings.Join(missing, "; "))
+		status = setCondition(status, klum.UserPolicyViolationCondition, len(violations) > 0, strings.Join(violations, "; "))
+	}
+
+	return objs, setReady(status, ready), true, nil
+}
+
+// getServiceAccount returns the ServiceAccount the user authenticates as and
+// its token Secret, users authenticating with certificates have none
+func (h *handler) getServiceAccount(user string) ([]runtime.Object, error) {
+	if !h.usesServiceAccounts() {
+		return nil, nil
 	}
 
 	objs := []runtime.Object{
 		&v1.ServiceAccount{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      user.Name,
+				Name:      user,
 				Namespace: h.cfg.Namespace,
 				Annotations: map[string]string{
-					"klum.cattle.io/user": user.Name,
+					"klum.cattle.io/user": user,
 				},
 			},
 		},
 	}
-	objs = append(objs, h.getRoles(user)...)
 
-	return objs, setReady(status, true), nil
+	tokenSecret, err := h.getTokenSecret(user)
+	if err != nil {
+		return nil, err
+	}
+	return append(objs, tokenSecret...), nil
+}
+
+func (h *handler) usesServiceAccounts() bool {
+	return h.cfg.CredentialMode == CredentialModeSecret || h.cfg.CredentialMode == CredentialModeTokenRequest ||
+		h.cfg.CredentialMode == CredentialModeExec
 }
 
-func (h *handler) getRoles(user *klum.User) []runtime.Object {
-	subjects := []rbacv1.Subject{
+func (h *handler) subjects(user string, spec klum.UserSpec) []rbacv1.Subject {
+	if h.cfg.CredentialMode == CredentialModeOIDC {
+		return h.oidcSubjects(spec)
+	}
+	if !h.usesServiceAccounts() {
+		return []rbacv1.Subject{
+			{
+				Kind:     "User",
+				APIGroup: "rbac.authorization.k8s.io",
+				Name:     user,
+			},
+		}
+	}
+	return []rbacv1.Subject{
 		{
 			Kind:      "ServiceAccount",
-			Name:      user.Name,
+			Name:      user,
 			Namespace: h.cfg.Namespace,
 		},
 	}
+}
 
-	if len(user.Spec.ClusterRoles) == 0 && len(user.Spec.Roles) == 0 {
-		if h.cfg.DefaultClusterRole == "" {
-			return nil
-		}
-		return []runtime.Object{
-			&rbacv1.ClusterRoleBinding{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: name(user.Name, "", h.cfg.DefaultClusterRole, ""),
-				},
-				Subjects: subjects,
-				RoleRef: rbacv1.RoleRef{
-					APIGroup: "rbac.authorization.k8s.io",
-					Kind:     "ClusterRole",
-					Name:     h.cfg.DefaultClusterRole,
-				},
-			},
-		}
+// username returns the name the apiserver authenticates the user as, OIDC
+// users without their username claim have none
+func (h *handler) username(user string, spec klum.UserSpec) string {
+	subjects := h.subjects(user, spec)
+	if len(subjects) == 0 {
+		return ""
+	}
+	if subjects[0].Kind == "ServiceAccount" {
+		return fmt.Sprintf("system:serviceaccount:%s:%s", subjects[0].Namespace, subjects[0].Name)
 	}
+	return subjects[0].Name
+}
+
+// ensureCredential issues or renews the Kubeconfig of an active user. The
+// Kubeconfigs of users authenticating as a ServiceAccount are deleted with
+// the ServiceAccount, others are deleted here once the user isn't active.
+func (h *handler) ensureCredential(user *klum.User, active bool) error {
+	switch {
+	case h.cfg.CredentialMode == CredentialModeTokenRequest && active:
+		return h.ensureToken(user)
+	case h.cfg.CredentialMode == CredentialModeCertificate && active:
+		return h.ensureCertificate(user)
+	case h.cfg.CredentialMode == CredentialModeCA && active:
+		return h.ensureSignedCertificate(user)
+	case h.cfg.CredentialMode == CredentialModeExec && active:
+		return h.ensureExecCredential(user)
+	case h.cfg.CredentialMode == CredentialModeOIDC && active:
+		return h.ensureOIDCKubeconfig(user)
+	case !h.usesServiceAccounts() && !active:
+		return h.deleteKubeconfig(user.Name)
+	}
+	return nil
+}
+
+// getRoles returns the roles and bindings granting the subjects of the user
+// the roles in the spec and a description of every reference to a role or
+// namespace that could not be resolved.
+func (h *handler) getRoles(user string, spec klum.UserSpec, subjects []rbacv1.Subject) ([]runtime.Object, []string, error) {
 
-	var objs []runtime.Object
+	var (
+		objs    []runtime.Object
+		missing = &unresolved{}
+	)
 
-	for _, clusterRole := range user.Spec.ClusterRoles {
+	for _, clusterRole := range spec.ClusterRoles {
+		if err := missing.clusterRole(h.clusterRoles, clusterRole); err != nil {
+			return nil, nil, err
+		}
 		objs = append(objs, &rbacv1.ClusterRoleBinding{
 			ObjectMeta: metav1.ObjectMeta{
-				Name: name(user.Name, "", clusterRole, ""),
+				Name: name(user, "", clusterRole, ""),
 			},
 			Subjects: subjects,
 			RoleRef: rbacv1.RoleRef{
@@ -132,16 +427,94 @@ func (h *handler) getRoles(user *klum.User) []runtime.Object {
 		})
 	}
 
-	for _, role := range user.Spec.Roles {
-		if role.Namespace == "" ||
-			role.Role == "" && role.ClusterRole == "" {
+	for _, template := range spec.RoleTemplates {
+		if err := missing.roleTemplate(h.roleTemplates, template); err != nil {
+			return nil, nil, err
+		}
+		ok, err := h.clusterScopedTemplate(template)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			missing.addf("skipped roleTemplate %s: not a cluster scoped template", template)
 			continue
 		}
+		objs = append(objs, &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name(user, "", roleTemplateName(template), ""),
+			},
+			Subjects: subjects,
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: "rbac.authorization.k8s.io",
+				Kind:     "ClusterRole",
+				Name:     roleTemplateName(template),
+			},
+		})
+	}
+
+	if len(spec.Rules) > 0 {
+		objs = append(objs, &rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: rulesName(user, ""),
+			},
+			Rules: spec.Rules,
+		}, &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: rulesName(user, ""),
+			},
+			Subjects: subjects,
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: "rbac.authorization.k8s.io",
+				Kind:     "ClusterRole",
+				Name:     rulesName(user, ""),
+			},
+		})
+	}
+
+	var (
+		ruleNamespaces []string
+		namespaceRules = map[string][]rbacv1.PolicyRule{}
+	)
+
+	roles, err := h.expandNamespaces(spec.Roles)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	for _, role := range roles {
+		if expired(role.ExpiresAt, now) {
+			continue
+		}
+		if role.Role == "" && role.ClusterRole == "" && role.RoleTemplate == "" && len(role.Rules) == 0 {
+			missing.addf("skipped role in namespace %q: no role, clusterRole, roleTemplate or rules", role.Namespace)
+			continue
+		}
+		if role.Namespace == "" {
+			missing.addf("skipped %s: namespace is empty", describeRole(role))
+			continue
+		}
+		if ok, err := missing.namespace(h.namespaces, role.Namespace); err != nil {
+			return nil, nil, err
+		} else if !ok {
+			continue
+		}
+
+		if len(role.Rules) > 0 {
+			// multiple entries for the same namespace share one Role
+			if _, ok := namespaceRules[role.Namespace]; !ok {
+				ruleNamespaces = append(ruleNamespaces, role.Namespace)
+			}
+			namespaceRules[role.Namespace] = append(namespaceRules[role.Namespace], role.Rules...)
+		}
 
 		if role.Role != "" {
+			if err := missing.role(h.roles, role.Namespace, role.Role); err != nil {
+				return nil, nil, err
+			}
 			rb := &rbacv1.RoleBinding{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      name(user.Name, role.Namespace, "", role.Role),
+					Name:      name(user, role.Namespace, "", role.Role),
 					Namespace: role.Namespace,
 				},
 				Subjects: subjects,
@@ -154,10 +527,32 @@ func (h *handler) getRoles(user *klum.User) []runtime.Object {
 			objs = append(objs, rb)
 		}
 
+		if role.RoleTemplate != "" {
+			if err := missing.roleTemplate(h.roleTemplates, role.RoleTemplate); err != nil {
+				return nil, nil, err
+			}
+			rb := &rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name(user, role.Namespace, roleTemplateName(role.RoleTemplate), ""),
+					Namespace: role.Namespace,
+				},
+				Subjects: subjects,
+				RoleRef: rbacv1.RoleRef{
+					APIGroup: "rbac.authorization.k8s.io",
+					Kind:     "ClusterRole",
+					Name:     roleTemplateName(role.RoleTemplate),
+				},
+			}
+			objs = append(objs, rb)
+		}
+
 		if role.ClusterRole != "" {
+			if err := missing.clusterRole(h.clusterRoles, role.ClusterRole); err != nil {
+				return nil, nil, err
+			}
 			rb := &rbacv1.RoleBinding{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      name(user.Name, role.Namespace, role.ClusterRole, ""),
+					Name:      name(user, role.Namespace, role.ClusterRole, ""),
 					Namespace: role.Namespace,
 				},
 				Subjects: subjects,
@@ -171,7 +566,28 @@ func (h *handler) getRoles(user *klum.User) []runtime.Object {
 		}
 	}
 
-	return objs
+	for _, namespace := range ruleNamespaces {
+		objs = append(objs, &rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      rulesName(user, namespace),
+				Namespace: namespace,
+			},
+			Rules: namespaceRules[namespace],
+		}, &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      rulesName(user, namespace),
+				Namespace: namespace,
+			},
+			Subjects: subjects,
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: "rbac.authorization.k8s.io",
+				Kind:     "Role",
+				Name:     rulesName(user, namespace),
+			},
+		})
+	}
+
+	return objs, missing.messages, nil
 }
 
 func name(user, namespace, clusterRole, role string) string {
@@ -183,9 +599,16 @@ func name(user, namespace, clusterRole, role string) string {
 	return name2.SafeConcatName("klum", user, role, hex.EncodeToString(suffix[:])[:8])
 }
 
+// rulesName is the name of the Role or ClusterRole (and its binding) holding the
+// inline rules of a user. An empty namespace means the cluster scoped rules.
+func rulesName(user, namespace string) string {
+	suffix := md5.Sum([]byte(fmt.Sprintf("%s/%s/rules", user, namespace)))
+	return name2.SafeConcatName("klum", user, "rules", hex.EncodeToString(suffix[:])[:8])
+}
+
 func (h *handler) OnSecretChange(key string, secret *v1.Secret) (*v1.Secret, error) {
-	if secret == nil {
-		return nil, nil
+	if secret == nil || h.cfg.CredentialMode != CredentialModeSecret {
+		return secret, nil
 	}
 
 	if secret.Type != v1.SecretTypeServiceAccountToken {
@@ -208,54 +631,54 @@ func (h *handler) OnSecretChange(key string, secret *v1.Secret) (*v1.Secret, err
 		return secret, nil
 	}
 
+	// Secrets created by the controller are empty until Kubernetes issues the token
+	if len(secret.Data["token"]) == 0 {
+		return secret, nil
+	}
+
 	ca := h.cfg.CA
 	if ca == "" {
 		ca = base64.StdEncoding.EncodeToString(secret.Data["ca.crt"])
 	}
 	token := string(secret.Data["token"])
 
-	return secret, h.apply.
-		WithOwner(secret).
-		WithSetOwnerReference(true, false).
-		ApplyObjects(&klum.Kubeconfig{
-			ObjectMeta: metav1.ObjectMeta{
-				Name: userName,
-			},
-			Spec: klum.KubeconfigSpec{
-				Clusters: []klum.NamedCluster{
-					{
-						Name: h.cfg.ContextName,
-						Cluster: klum.Cluster{
-							Server:                   h.cfg.Server,
-							CertificateAuthorityData: ca,
-						},
-					},
-				},
-				AuthInfos: []klum.NamedAuthInfo{
-					{
-						Name: h.cfg.ContextName,
-						AuthInfo: klum.AuthInfo{
-							Token: token,
-						},
-					},
-				},
-				Contexts: []klum.NamedContext{
-					{
-						Name: h.cfg.ContextName,
-						Context: klum.Context{
-							Cluster:  h.cfg.ContextName,
-							AuthInfo: h.cfg.ContextName,
-						},
-					},
-				},
-				CurrentContext: h.cfg.ContextName,
-			},
-		})
+	user, err := h.users.Get(userName)
+	if errors.IsNotFound(err) {
+		return secret, nil
+	} else if err != nil {
+		return secret, err
+	}
+	namespaces, currentNamespace, err := h.userNamespaces(user)
+	if err != nil {
+		return secret, err
+	}
+
+	return secret, h.applyKubeconfig(secret, h.kubeconfig(userName, ca, klum.AuthInfo{
+		Token: token,
+	}, namespaces, currentNamespace), klum.KubeconfigStatus{
+		IssuedAt: secret.CreationTimestamp.DeepCopy(),
+	})
 }
 
 func setReady(status klum.UserStatus, ready bool) klum.UserStatus {
+	return setCondition(status, klum.UserReadyCondition, ready, "")
+}
+
+func setCondition(status klum.UserStatus, cond condition.Cond, value bool, message string) klum.UserStatus {
 	// dumb hack to set condition, should really make this easier
 	user := &klum.User{Status: status}
-	klum.UserReadyCondition.SetStatusBool(user, ready)
+	cond.SetStatusBool(user, value)
+	cond.Message(user, message)
 	return user.Status
 }
+
+func removeCondition(status klum.UserStatus, cond condition.Cond) klum.UserStatus {
+	var conditions []genericcondition.GenericCondition
+	for _, c := range status.Conditions {
+		if c.Type != string(cond) {
+			conditions = append(conditions, c)
+		}
+	}
+	status.Conditions = conditions
+	return status
+}
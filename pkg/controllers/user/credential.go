@@ -0,0 +1,395 @@
+package user
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	"github.com/rancher/wrangler/pkg/condition"
+	name2 "github.com/rancher/wrangler/pkg/name"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const credentialByUser = "klum.cattle.io/credential-by-user"
+
+// credentialPrefix starts the names of the ServiceAccounts and Kubeconfigs of
+// credentials, users can't have names starting with it
+const credentialPrefix = "klum-credential"
+
+func indexCredentialByUser(obj *klum.Credential) ([]string, error) {
+	return []string{obj.Spec.User}, nil
+}
+
+// credentialName is the name of the ServiceAccount and Kubeconfig of a
+// credential, which also names its roles and bindings. Names of credentials
+// are unique, the prefix keeps them apart from the names of users.
+func credentialName(cred *klum.Credential) string {
+	return name2.SafeConcatName(credentialPrefix, cred.Name)
+}
+
+// reservedUserName returns whether the objects of a user with the name would
+// collide with the ones of a credential
+func reservedUserName(name string) bool {
+	return strings.HasPrefix(name, credentialPrefix+"-")
+}
+
+// OnCredentialChange generates the ServiceAccount and bindings of a credential
+// while its user is active and the credential hasn't expired, and issues its
+// Kubeconfig once the ServiceAccount exists.
+func (h *handler) OnCredentialChange(cred *klum.Credential, status klum.CredentialStatus) ([]runtime.Object, klum.CredentialStatus, error) {
+	now := time.Now()
+	status.ServiceAccountName = ""
+	status.KubeconfigName = ""
+	status.Bindings = nil
+
+	status.ExpiresAt = nil
+	if cred.Spec.TTL != nil {
+		status.ExpiresAt = &metav1.Time{Time: cred.CreationTimestamp.Add(cred.Spec.TTL.Duration)}
+		if expired(status.ExpiresAt, now) {
+			status = setCredentialCondition(status, klum.CredentialExpiredCondition, true,
+				"expired at "+status.ExpiresAt.UTC().Format(time.RFC3339))
+			return nil, setCredentialCondition(status, klum.CredentialReadyCondition, false, ""), nil
+		}
+		h.credentialController.EnqueueAfter(cred.Name, status.ExpiresAt.Sub(now)+time.Second)
+	}
+	status = setCredentialCondition(status, klum.CredentialExpiredCondition, false, "")
+
+	user, err := h.users.Get(cred.Spec.User)
+	if errors.IsNotFound(err) {
+		return nil, setCredentialCondition(status, klum.CredentialReadyCondition, false,
+			fmt.Sprintf("user %s not found", cred.Spec.User)), nil
+	} else if err != nil {
+		return nil, status, err
+	}
+	if reason := inactive(user, now); reason != "" {
+		return nil, setCredentialCondition(status, klum.CredentialReadyCondition, false, reason), nil
+	}
+
+	spec, err := h.effectiveSpec(user)
+	if err != nil {
+		return nil, status, err
+	}
+	spec, notGranted := credentialSpec(cred, spec)
+
+	saName := credentialName(cred)
+	sa := &v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      saName,
+			Namespace: h.cfg.Namespace,
+			Annotations: map[string]string{
+				"klum.cattle.io/credential": cred.Name,
+			},
+		},
+	}
+	objs := []runtime.Object{sa}
+
+	roles, missing, err := h.getRoles(saName, spec, []rbacv1.Subject{
+		{
+			Kind:      "ServiceAccount",
+			Name:      saName,
+			Namespace: h.cfg.Namespace,
+		},
+	})
+	if err != nil {
+		return nil, status, err
+	}
+	roles, violations, err := h.enforcePolicies(roles)
+	if err != nil {
+		return nil, status, err
+	}
+	objs = append(objs, roles...)
+
+	missing = append(notGranted, missing...)
+	status = setCredentialCondition(status, klum.CredentialRolesResolvedCondition, len(missing) == 0, strings.Join(missing, "; "))
+	status = setCredentialCondition(status, klum.CredentialReadyCondition, len(missing) == 0 && len(violations) == 0,
+		strings.Join(violations, "; "))
+	status.ServiceAccountName = saName
+	status.Bindings = bindings(roles)
+
+	issued, tokenSecret, err := h.ensureCredentialToken(cred, spec, saName)
+	if err != nil {
+		return nil, status, err
+	}
+	objs = append(objs, tokenSecret...)
+	if issued {
+		status.KubeconfigName = saName
+	}
+	return objs, status, nil
+}
+
+// inactive returns why the credentials of a user are revoked, that is if the
+// user is disabled, expired or outside of its schedule
+func inactive(user *klum.User, now time.Time) string {
+	if user.Spec.Enabled != nil && !*user.Spec.Enabled {
+		return fmt.Sprintf("user %s is disabled", user.Name)
+	}
+	if expired(user.Spec.ExpiresAt, now) {
+		return fmt.Sprintf("user %s has expired", user.Name)
+	}
+	if user.Spec.Schedule != nil {
+		if active, _, err := evaluateSchedule(user.Spec.Schedule, now); err != nil || !active {
+			return fmt.Sprintf("user %s is outside of its schedule", user.Name)
+		}
+	}
+	return ""
+}
+
+// credentialSpec returns the roles of the user that are granted to the
+// credential and a description of every role of the credential the user
+// doesn't have
+func credentialSpec(cred *klum.Credential, spec klum.UserSpec) (klum.UserSpec, []string) {
+	if len(cred.Spec.ClusterRoles) == 0 && len(cred.Spec.Roles) == 0 {
+		return spec, nil
+	}
+
+	var (
+		result     klum.UserSpec
+		notGranted []string
+	)
+
+	granted := map[string]bool{}
+	for _, clusterRole := range spec.ClusterRoles {
+		granted[clusterRole] = true
+	}
+
+	for _, clusterRole := range cred.Spec.ClusterRoles {
+		if !granted[clusterRole] {
+			notGranted = append(notGranted, fmt.Sprintf("skipped clusterRole %s: not granted to user %s", clusterRole, cred.Spec.User))
+			continue
+		}
+		result.ClusterRoles = append(result.ClusterRoles, clusterRole)
+	}
+
+	for _, role := range cred.Spec.Roles {
+		userRole, ok := grantedRole(spec.Roles, role)
+		if !ok {
+			notGranted = append(notGranted, fmt.Sprintf("skipped %s: not granted to user %s", describeRole(role), cred.Spec.User))
+			continue
+		}
+		// the role expires along with the role of the user
+		result.Roles = append(result.Roles, userRole)
+	}
+
+	return result, notGranted
+}
+
+// grantedRole returns the role of the user that is the same as role, apart
+// from when it expires
+func grantedRole(roles []klum.NamespaceRole, role klum.NamespaceRole) (klum.NamespaceRole, bool) {
+	role.ExpiresAt = nil
+	for _, granted := range roles {
+		candidate := *granted.DeepCopy()
+		candidate.ExpiresAt = nil
+		if equality.Semantic.DeepEqual(candidate, role) {
+			return granted, true
+		}
+	}
+	return klum.NamespaceRole{}, false
+}
+
+// ensureCredentialToken issues the Kubeconfig of the credential with a token
+// of its ServiceAccount once the ServiceAccount exists, and returns whether it
+// did and the token Secret to create. In secret mode the token Secret is used
+// like for users, klum creates it for clusters that don't. Credentials have
+// no certificates, refresh tokens or OIDC identities, so every other mode uses
+// expiring tokens from the TokenRequest API.
+func (h *handler) ensureCredentialToken(cred *klum.Credential, spec klum.UserSpec, saName string) (bool, []runtime.Object, error) {
+	sa, err := h.serviceAccounts.Get(h.cfg.Namespace, saName)
+	if errors.IsNotFound(err) {
+		// the credential is enqueued again once the ServiceAccount is created
+		return false, nil, nil
+	} else if err != nil {
+		return false, nil, err
+	}
+
+	if h.cfg.CredentialMode != CredentialModeSecret {
+		issued, err := h.ensureCredentialTokenRequest(cred, spec, sa)
+		return issued, nil, err
+	}
+
+	if h.cfg.TokenSecretsCreated {
+		// the ServiceAccount is updated once Kubernetes created the Secret
+		secret, err := h.tokenSecret(saName)
+		if err != nil || secret == nil {
+			return false, nil, err
+		}
+		issued, err := h.ensureCredentialKubeconfig(cred, spec, secret.Name)
+		return issued, nil, err
+	}
+
+	secret := credentialTokenSecret(cred, sa)
+	issued, err := h.ensureCredentialKubeconfig(cred, spec, secret.Name)
+	return issued, []runtime.Object{secret}, err
+}
+
+// credentialTokenSecret returns the token Secret of the ServiceAccount of the
+// credential for clusters that don't create one
+func credentialTokenSecret(cred *klum.Credential, sa *v1.ServiceAccount) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name2.SafeConcatName(sa.Name, "token"),
+			Namespace: sa.Namespace,
+			Annotations: map[string]string{
+				"klum.cattle.io/credential":          cred.Name,
+				"kubernetes.io/service-account.name": sa.Name,
+				"kubernetes.io/service-account.uid":  string(sa.UID),
+			},
+		},
+		Type: v1.SecretTypeServiceAccountToken,
+	}
+}
+
+// ensureCredentialTokenRequest issues the Kubeconfig of the credential with a
+// token from the TokenRequest API when it has none or its token is due for
+// renewal, and requeues the credential for the next renewal. It returns
+// whether the credential has a Kubeconfig.
+func (h *handler) ensureCredentialTokenRequest(cred *klum.Credential, spec klum.UserSpec, sa *v1.ServiceAccount) (bool, error) {
+	now := time.Now()
+	kubeconfig, err := h.kubeconfigs.Get(sa.Name)
+	if err == nil && kubeconfig.Status.IssuedAt != nil && kubeconfig.Status.ExpiresAt != nil {
+		renew := renewAt(kubeconfig.Status.IssuedAt.Time, kubeconfig.Status.ExpiresAt.Time)
+		if now.Before(renew) {
+			h.credentialController.EnqueueAfter(cred.Name, renew.Sub(now))
+			return true, nil
+		}
+	} else if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+
+	token, err := h.requestToken(sa.Name)
+	if err != nil {
+		return false, err
+	}
+	ca, err := h.clusterCA()
+	if err != nil {
+		return false, err
+	}
+
+	namespaces, currentNamespace := contextNamespaces(spec, "")
+	kubeconfig = h.kubeconfig(sa.Name, ca, klum.AuthInfo{
+		Token: token.Status.Token,
+	}, namespaces, currentNamespace)
+	kubeconfig.Labels["klum.cattle.io/user"] = cred.Spec.User
+	kubeconfig.Labels["klum.cattle.io/credential"] = cred.Name
+
+	expires := token.Status.ExpirationTimestamp
+	err = h.applyKubeconfig(sa, kubeconfig, klum.KubeconfigStatus{
+		IssuedAt:  &metav1.Time{Time: now},
+		ExpiresAt: &expires,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	h.credentialController.EnqueueAfter(cred.Name, renewAt(now, expires.Time).Sub(now))
+	return true, nil
+}
+
+// ensureCredentialKubeconfig issues the Kubeconfig of the credential, with a
+// context for every namespace of its roles, owned by its token Secret, and
+// returns whether it did
+func (h *handler) ensureCredentialKubeconfig(cred *klum.Credential, spec klum.UserSpec, secretName string) (bool, error) {
+	secret, err := h.secrets.Get(h.cfg.Namespace, secretName)
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	// the Secret is empty until Kubernetes issues the token
+	if len(secret.Data["token"]) == 0 {
+		return false, nil
+	}
+
+	ca := h.cfg.CA
+	if ca == "" {
+		ca = base64.StdEncoding.EncodeToString(secret.Data["ca.crt"])
+	}
+
+	namespaces, currentNamespace := contextNamespaces(spec, "")
+	kubeconfig := h.kubeconfig(credentialName(cred), ca, klum.AuthInfo{
+		Token: string(secret.Data["token"]),
+	}, namespaces, currentNamespace)
+	kubeconfig.Labels["klum.cattle.io/user"] = cred.Spec.User
+	kubeconfig.Labels["klum.cattle.io/credential"] = cred.Name
+	return true, h.applyKubeconfig(secret, kubeconfig, klum.KubeconfigStatus{
+		IssuedAt: secret.CreationTimestamp.DeepCopy(),
+	})
+}
+
+// resolveCredentialUser enqueues the credentials of a user
+func (h *handler) resolveCredentialUser(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	if _, ok := obj.(*klum.User); !ok {
+		return nil, nil
+	}
+	creds, err := h.credentials.GetByIndex(credentialByUser, name)
+	if err != nil {
+		return nil, err
+	}
+	var keys []relatedresource.Key
+	for _, cred := range creds {
+		keys = append(keys, relatedresource.NewKey("", cred.Name))
+	}
+	return keys, nil
+}
+
+// resolveUnresolvedCredentials enqueues the credentials referencing a role or
+// namespace that didn't exist when they were last handled
+func (h *handler) resolveUnresolvedCredentials(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	creds, err := h.credentials.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var keys []relatedresource.Key
+	for _, cred := range creds {
+		if klum.CredentialRolesResolvedCondition.IsFalse(cred) {
+			keys = append(keys, relatedresource.NewKey("", cred.Name))
+		}
+	}
+	return keys, nil
+}
+
+// resolveCredentialObject enqueues the credential of a ServiceAccount, token
+// Secret or Kubeconfig
+func (h *handler) resolveCredentialObject(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	switch o := obj.(type) {
+	case *v1.ServiceAccount:
+		if o.Annotations["klum.cattle.io/credential"] != "" {
+			return []relatedresource.Key{relatedresource.NewKey("", o.Annotations["klum.cattle.io/credential"])}, nil
+		}
+	case *v1.Secret:
+		if o.Annotations["klum.cattle.io/credential"] != "" {
+			return []relatedresource.Key{relatedresource.NewKey("", o.Annotations["klum.cattle.io/credential"])}, nil
+		}
+		if o.Type == KubeconfigSecretType && o.Labels["klum.cattle.io/kubeconfig"] != "" {
+			kubeconfig, err := h.kubeconfigs.Get(o.Labels["klum.cattle.io/kubeconfig"])
+			if errors.IsNotFound(err) {
+				return nil, nil
+			} else if err != nil {
+				return nil, err
+			}
+			return h.resolveCredentialObject("", kubeconfig.Name, kubeconfig)
+		}
+	case *klum.Kubeconfig:
+		if o.Labels["klum.cattle.io/credential"] != "" {
+			return []relatedresource.Key{relatedresource.NewKey("", o.Labels["klum.cattle.io/credential"])}, nil
+		}
+	}
+	return nil, nil
+}
+
+func setCredentialCondition(status klum.CredentialStatus, cond condition.Cond, value bool, message string) klum.CredentialStatus {
+	cred := &klum.Credential{Status: status}
+	cond.SetStatusBool(cred, value)
+	cond.Message(cred, message)
+	return cred.Status
+}
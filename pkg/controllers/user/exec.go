@@ -0,0 +1,120 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	name2 "github.com/rancher/wrangler/pkg/name"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// CredentialModeExec puts an exec plugin in Kubeconfigs that fetches
+	// short-lived tokens from the credential server with a refresh token
+	CredentialModeExec = "exec"
+
+	// RefreshSecretType is the type of the Secrets holding refresh tokens
+	RefreshSecretType v1.SecretType = "klum.cattle.io/refresh-token"
+	// RefreshTokenKey is the key of the refresh token in its Secret
+	RefreshTokenKey = "token"
+	// RefreshTokenEnv is the variable the exec plugin reads the refresh token from
+	RefreshTokenEnv = "KLUM_REFRESH_TOKEN"
+
+	execAPIVersion = "client.authentication.k8s.io/v1beta1"
+)
+
+// RefreshSecretName returns the name of the Secret holding the refresh token
+// of the user
+func RefreshSecretName(user string) string {
+	return name2.SafeConcatName(user, "refresh")
+}
+
+// ensureExecCredential issues a Kubeconfig with an exec plugin once the
+// ServiceAccount of the user exists. The refresh token is owned by the
+// ServiceAccount, so it is revoked along with it, and owns the Kubeconfig.
+func (h *handler) ensureExecCredential(user *klum.User) error {
+	sa, err := h.serviceAccounts.Get(h.cfg.Namespace, user.Name)
+	if errors.IsNotFound(err) {
+		// the user is enqueued again once the ServiceAccount is created
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	secret, err := h.secrets.Get(h.cfg.Namespace, RefreshSecretName(user.Name))
+	if errors.IsNotFound(err) {
+		secret, err = h.createRefreshSecret(sa)
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(secret, sa) {
+		// the refresh token of a deleted ServiceAccount, the user is enqueued
+		// again once it is garbage collected
+		return nil
+	}
+
+	namespaces, currentNamespace, err := h.userNamespaces(user)
+	if err != nil {
+		return err
+	}
+	ca, err := h.clusterCA()
+	if err != nil {
+		return err
+	}
+
+	return h.applyKubeconfig(secret, h.kubeconfig(user.Name, ca, klum.AuthInfo{
+		Exec: &klum.ExecConfig{
+			APIVersion: execAPIVersion,
+			Command:    "klum",
+			Args:       []string{"credential", "--server", h.cfg.CredentialServer, "--user", user.Name},
+			Env: []klum.ExecEnvVar{
+				{
+					Name:  RefreshTokenEnv,
+					Value: string(secret.Data[RefreshTokenKey]),
+				},
+			},
+			InstallHint: "klum is needed to authenticate, download it from https://github.com/ibuildthecloud/klum/releases",
+		},
+	}, namespaces, currentNamespace), klum.KubeconfigStatus{
+		IssuedAt: secret.CreationTimestamp.DeepCopy(),
+	})
+}
+
+func (h *handler) createRefreshSecret(sa *v1.ServiceAccount) (*v1.Secret, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	return h.coreClient.Secrets(h.cfg.Namespace).Create(context.TODO(), &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      RefreshSecretName(sa.Name),
+			Namespace: h.cfg.Namespace,
+			Annotations: map[string]string{
+				"klum.cattle.io/user": sa.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(sa, v1.SchemeGroupVersion.WithKind("ServiceAccount")),
+			},
+		},
+		Type: RefreshSecretType,
+		Data: map[string][]byte{
+			RefreshTokenKey: []byte(base64.RawURLEncoding.EncodeToString(token)),
+		},
+	}, metav1.CreateOptions{})
+}
+
+// resolveRefreshSecret enqueues the user of a refresh token Secret
+func resolveRefreshSecret(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	if secret, ok := obj.(*v1.Secret); ok && secret.Type == RefreshSecretType && secret.Annotations["klum.cattle.io/user"] != "" {
+		return []relatedresource.Key{relatedresource.NewKey("", secret.Annotations["klum.cattle.io/user"])}, nil
+	}
+	return nil, nil
+}
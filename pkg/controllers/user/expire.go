@@ -0,0 +1,62 @@
+package user
+
+import (
+	"time"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// countdownInterval is how often the remaining time of an expiring user is
+// refreshed in its status
+const countdownInterval = time.Hour
+
+func expired(t *metav1.Time, now time.Time) bool {
+	return t != nil && !now.Before(t.Time)
+}
+
+// setExpiration records the remaining time of the user in the status and
+// returns false if the user has expired.
+func setExpiration(user *klum.User, status klum.UserStatus, now time.Time) (klum.UserStatus, bool) {
+	if expired(user.Spec.ExpiresAt, now) {
+		status.ExpiresIn = ""
+		return setCondition(status, klum.UserExpiredCondition, true,
+			"expired at "+user.Spec.ExpiresAt.UTC().Format(time.RFC3339)), false
+	}
+
+	status.ExpiresIn = ""
+	if user.Spec.ExpiresAt != nil {
+		status.ExpiresIn = duration.HumanDuration(user.Spec.ExpiresAt.Sub(now))
+	}
+	return setCondition(status, klum.UserExpiredCondition, false, ""), true
+}
+
+// enqueueExpiration schedules the user to be handled again when the user or one
+// of its roles expires.
+func (h *handler) enqueueExpiration(user *klum.User, spec klum.UserSpec, now time.Time) {
+	var next time.Duration
+
+	schedule := func(t *metav1.Time) {
+		if t == nil || expired(t, now) {
+			return
+		}
+		// wake up just after the expiration, never right before it
+		if d := t.Sub(now) + time.Second; next == 0 || d < next {
+			next = d
+		}
+	}
+
+	schedule(user.Spec.ExpiresAt)
+	for _, role := range spec.Roles {
+		schedule(role.ExpiresAt)
+	}
+
+	if user.Spec.ExpiresAt != nil && next > countdownInterval {
+		next = countdownInterval
+	}
+
+	if next > 0 {
+		h.userController.EnqueueAfter(user.Name, next)
+	}
+}
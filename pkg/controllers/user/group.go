@@ -0,0 +1,105 @@
+package user
+
+import (
+	"sort"
+	"time"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// effectiveSpec returns the spec of the user with the roles of all the groups
+// the user is a member of and of its approved access requests added to it.
+func (h *handler) effectiveSpec(user *klum.User) (klum.UserSpec, error) {
+	spec := *user.Spec.DeepCopy()
+
+	groups, err := h.groups.List(labels.Everything())
+	if err != nil {
+		return spec, err
+	}
+
+	// keep the generated objects stable regardless of cache order
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+
+	for _, group := range groups {
+		member, err := isMember(group, user)
+		if err != nil {
+			return spec, err
+		}
+		if !member {
+			continue
+		}
+		spec.ClusterRoles = append(spec.ClusterRoles, group.Spec.ClusterRoles...)
+		spec.Roles = append(spec.Roles, group.Spec.Roles...)
+		spec.Rules = append(spec.Rules, group.Spec.Rules...)
+		spec.RoleTemplates = append(spec.RoleTemplates, group.Spec.RoleTemplates...)
+	}
+
+	// users without any roles of their own get the default role, requesting
+	// more access must not take it away
+	if len(spec.ClusterRoles) == 0 && len(spec.Roles) == 0 && len(spec.Rules) == 0 &&
+		len(spec.RoleTemplates) == 0 && h.cfg.DefaultClusterRole != "" {
+		spec.ClusterRoles = []string{h.cfg.DefaultClusterRole}
+	}
+
+	reqs, err := h.activeAccessRequests(user.Name, time.Now())
+	if err != nil {
+		return spec, err
+	}
+
+	for _, req := range reqs {
+		spec.ClusterRoles = append(spec.ClusterRoles, req.Spec.ClusterRoles...)
+		for _, role := range req.Spec.Roles {
+			role.ExpiresAt = req.Status.ExpiresAt
+			spec.Roles = append(spec.Roles, role)
+		}
+	}
+
+	return spec, nil
+}
+
+func isMember(group *klum.Group, user *klum.User) (bool, error) {
+	for _, member := range group.Spec.Members {
+		if member == user.Name {
+			return true, nil
+		}
+	}
+
+	if group.Spec.UserSelector == nil {
+		return false, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(group.Spec.UserSelector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(user.Labels)), nil
+}
+
+// resolveGroup enqueues every user when a group changes. Users that were
+// removed from the group need to be updated just as much as the current
+// members, and the old membership is not known at this point.
+func (h *handler) resolveGroup(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	if _, ok := obj.(*klum.Group); !ok {
+		return nil, nil
+	}
+	return h.allUsers()
+}
+
+func (h *handler) allUsers() ([]relatedresource.Key, error) {
+	users, err := h.users.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var result []relatedresource.Key
+	for _, user := range users {
+		result = append(result, relatedresource.NewKey("", user.Name))
+	}
+	return result, nil
+}
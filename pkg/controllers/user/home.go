@@ -0,0 +1,206 @@
+package user
+
+import (
+	"fmt"
+	"strings"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	"github.com/rancher/wrangler/pkg/kv"
+	name2 "github.com/rancher/wrangler/pkg/name"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	homeName      = "klum-home"
+	homeAdminRole = "admin"
+)
+
+// ParseResourceList parses a comma separated list of resource=quantity pairs
+// such as "requests.cpu=4,pods=20".
+func ParseResourceList(value string) (v1.ResourceList, error) {
+	result := v1.ResourceList{}
+	for k, v := range kv.SplitMap(value, ",") {
+		q, err := resource.ParseQuantity(v)
+		if err != nil {
+			return nil, err
+		}
+		result[v1.ResourceName(k)] = q
+	}
+	return result, nil
+}
+
+// homeNamespace returns the name of the home namespace of the user or "" if the
+// user has none or the namespace can't be its home.
+func (h *handler) homeNamespace(user *klum.User) string {
+	namespace := h.homeNamespaceName(user)
+	if namespace == "" || h.checkHomeNamespace(user, namespace) != nil {
+		return ""
+	}
+	return namespace
+}
+
+// homeNamespaceName returns the name of the home namespace the user asks for
+func (h *handler) homeNamespaceName(user *klum.User) string {
+	if user.Spec.HomeNamespace != nil && user.Spec.HomeNamespace.Name != "" {
+		return user.Spec.HomeNamespace.Name
+	}
+	if user.Spec.HomeNamespace == nil && !h.cfg.HomeNamespaces {
+		return ""
+	}
+	// user names may contain dots, namespace names may not
+	return name2.SafeConcatName(h.cfg.HomeNamespacePrefix + strings.Replace(user.Name, ".", "-", -1))
+}
+
+// checkHomeNamespace returns why the namespace can't be the home of the user.
+// A namespace that already exists is only managed, and deleted with the user,
+// if it was created as the home of the user.
+func (h *handler) checkHomeNamespace(user *klum.User, namespace string) error {
+	if msgs := validation.IsDNS1123Label(namespace); len(msgs) > 0 {
+		return fmt.Errorf("invalid home namespace %s: %s", namespace, strings.Join(msgs, ", "))
+	}
+	if namespace == h.cfg.Namespace || namespace == metav1.NamespaceDefault || strings.HasPrefix(namespace, "kube-") {
+		return fmt.Errorf("home namespace %s is reserved", namespace)
+	}
+
+	ns, err := h.namespaces.Get(namespace)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if ns.Annotations["klum.cattle.io/user"] != user.Name {
+		return fmt.Errorf("home namespace %s already exists and is not the home of user %s", namespace, user.Name)
+	}
+	return nil
+}
+
+// setHomeNamespaceCondition records whether the home namespace of the user
+// can be provisioned
+func (h *handler) setHomeNamespaceCondition(user *klum.User, status klum.UserStatus) (klum.UserStatus, bool) {
+	namespace := h.homeNamespaceName(user)
+	if namespace == "" {
+		return removeCondition(status, klum.UserHomeNamespaceCondition), true
+	}
+	if err := h.checkHomeNamespace(user, namespace); err != nil {
+		return setCondition(status, klum.UserHomeNamespaceCondition, false, err.Error()), false
+	}
+	return setCondition(status, klum.UserHomeNamespaceCondition, true, ""), true
+}
+
+// getHomeNamespace returns the home namespace of the user and the quota, limits
+// and network policy in it.
+func (h *handler) getHomeNamespace(user *klum.User) []runtime.Object {
+	namespace := h.homeNamespace(user)
+	if namespace == "" {
+		return nil
+	}
+
+	spec := klum.HomeNamespace{}
+	if user.Spec.HomeNamespace != nil {
+		spec = *user.Spec.HomeNamespace
+	}
+
+	objs := []runtime.Object{
+		&v1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: namespace,
+				Annotations: map[string]string{
+					"klum.cattle.io/user": user.Name,
+				},
+			},
+		},
+	}
+
+	quota := spec.ResourceQuota
+	if quota == nil && len(h.cfg.HomeNamespaceQuota) > 0 {
+		quota = &v1.ResourceQuotaSpec{
+			Hard: h.cfg.HomeNamespaceQuota,
+		}
+	}
+	if quota != nil {
+		objs = append(objs, &v1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      homeName,
+				Namespace: namespace,
+			},
+			Spec: *quota,
+		})
+	}
+
+	limits := spec.LimitRange
+	if limits == nil && len(h.cfg.HomeNamespaceLimits) > 0 {
+		limits = &v1.LimitRangeSpec{
+			Limits: []v1.LimitRangeItem{
+				{
+					Type:    v1.LimitTypeContainer,
+					Default: h.cfg.HomeNamespaceLimits,
+				},
+			},
+		}
+	}
+	if limits != nil {
+		objs = append(objs, &v1.LimitRange{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      homeName,
+				Namespace: namespace,
+			},
+			Spec: *limits,
+		})
+	}
+
+	if spec.Isolated == nil || *spec.Isolated {
+		// only pods in the same namespace may connect to pods in the namespace
+		objs = append(objs, &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      homeName,
+				Namespace: namespace,
+			},
+			Spec: networkingv1.NetworkPolicySpec{
+				PolicyTypes: []networkingv1.PolicyType{
+					networkingv1.PolicyTypeIngress,
+				},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{
+					{
+						From: []networkingv1.NetworkPolicyPeer{
+							{
+								PodSelector: &metav1.LabelSelector{},
+							},
+						},
+					},
+				},
+			},
+		})
+	}
+
+	return objs
+}
+
+// getHomeBinding makes the user admin of its home namespace
+func (h *handler) getHomeBinding(user *klum.User) []runtime.Object {
+	namespace := h.homeNamespace(user)
+	if namespace == "" {
+		return nil
+	}
+
+	return []runtime.Object{
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name(user.Name, namespace, homeAdminRole, ""),
+				Namespace: namespace,
+			},
+			Subjects: h.subjects(user.Name, user.Spec),
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: "rbac.authorization.k8s.io",
+				Kind:     "ClusterRole",
+				Name:     homeAdminRole,
+			},
+		},
+	}
+}
@@ -0,0 +1,267 @@
+package user
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	name2 "github.com/rancher/wrangler/pkg/name"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// rootCAConfigMap is published in every namespace by Kubernetes 1.20 and newer
+const rootCAConfigMap = "kube-root-ca.crt"
+
+// kubeconfig returns the Kubeconfig of the user authenticating with authInfo
+// with a context for the cluster and one for every namespace. The current
+// context is the one of currentNamespace, or the cluster if it is empty.
+func (h *handler) kubeconfig(user, ca string, authInfo klum.AuthInfo, namespaces []string, currentNamespace string) *klum.Kubeconfig {
+	contexts, current := h.contexts(namespaces, currentNamespace)
+	return &klum.Kubeconfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: user,
+			Labels: map[string]string{
+				"klum.cattle.io/user": user,
+			},
+		},
+		Spec: klum.KubeconfigSpec{
+			Clusters: []klum.NamedCluster{
+				{
+					Name: h.cfg.ContextName,
+					Cluster: klum.Cluster{
+						Server:                   h.cfg.Server,
+						CertificateAuthorityData: ca,
+					},
+				},
+			},
+			AuthInfos: []klum.NamedAuthInfo{
+				{
+					Name:     h.cfg.ContextName,
+					AuthInfo: authInfo,
+				},
+			},
+			Contexts:       contexts,
+			CurrentContext: current,
+		},
+	}
+}
+
+// contexts returns the context for the cluster and one for every namespace,
+// and the name of the context of currentNamespace
+func (h *handler) contexts(namespaces []string, currentNamespace string) ([]klum.NamedContext, string) {
+	contexts := []klum.NamedContext{
+		{
+			Name: h.cfg.ContextName,
+			Context: klum.Context{
+				Cluster:  h.cfg.ContextName,
+				AuthInfo: h.cfg.ContextName,
+			},
+		},
+	}
+	current := h.cfg.ContextName
+
+	for _, namespace := range namespaces {
+		name := name2.SafeConcatName(h.cfg.ContextName, namespace)
+		contexts = append(contexts, klum.NamedContext{
+			Name: name,
+			Context: klum.Context{
+				Cluster:   h.cfg.ContextName,
+				AuthInfo:  h.cfg.ContextName,
+				Namespace: namespace,
+			},
+		})
+		if namespace == currentNamespace {
+			current = name
+		}
+	}
+
+	return contexts, current
+}
+
+// userNamespaces returns the namespaces the user has roles in, starting with
+// its home namespace, and the namespace of its current context
+func (h *handler) userNamespaces(user *klum.User) ([]string, string, error) {
+	spec, err := h.effectiveSpec(user)
+	if err != nil {
+		return nil, "", err
+	}
+	namespaces, current := contextNamespaces(spec, h.homeNamespace(user))
+	return namespaces, current, nil
+}
+
+// contextNamespaces returns the namespaces of the roles in the spec that
+// aren't patterns or selectors and the namespace of the current context, which is empty
+// for the cluster
+func contextNamespaces(spec klum.UserSpec, home string) ([]string, string) {
+	var (
+		namespaces []string
+		seen       = map[string]bool{}
+	)
+	add := func(namespace string) {
+		if namespace != "" && !seen[namespace] && !strings.ContainsAny(namespace, `*?[\`) {
+			seen[namespace] = true
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	add(home)
+	for _, role := range spec.Roles {
+		if role.NamespaceSelector == nil {
+			add(role.Namespace)
+		}
+	}
+	add(spec.DefaultNamespace)
+
+	switch {
+	case spec.DefaultNamespace != "":
+		return namespaces, spec.DefaultNamespace
+	case len(namespaces) == 0 || len(spec.ClusterRoles) > 0 || len(spec.Rules) > 0 || len(spec.RoleTemplates) > 0:
+		return namespaces, ""
+	}
+	return namespaces, namespaces[0]
+}
+
+// syncKubeconfig updates the contexts of the Kubeconfig of the user when its
+// namespaces changed without its credential being issued again, encrypts it
+// when the user got a public key and restores the Secret holding it
+func (h *handler) syncKubeconfig(user *klum.User) error {
+	kubeconfig, err := h.kubeconfigs.Get(user.Name)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	namespaces, currentNamespace, err := h.userNamespaces(user)
+	if err != nil {
+		return err
+	}
+	if encrypted(kubeconfig) {
+		return h.syncEncryptedKubeconfig(user, kubeconfig, namespaces, currentNamespace)
+	}
+	spec, err := h.kubeconfigSpec(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	desired := kubeconfig.DeepCopy()
+	desired.Spec = spec
+	desired.Spec.Contexts, desired.Spec.CurrentContext = h.contexts(namespaces, currentNamespace)
+	desired, secret, err := h.kubeconfigSecret(desired)
+	if err != nil {
+		return err
+	}
+
+	if !equality.Semantic.DeepEqual(kubeconfig.Spec, desired.Spec) || !equality.Semantic.DeepEqual(kubeconfig.Annotations, desired.Annotations) {
+		if kubeconfig, err = h.kubeconfigController.Update(desired); err != nil {
+			return err
+		}
+	}
+	return h.updateKubeconfigStatus(kubeconfig, secret, kubeconfig.Status)
+}
+
+// syncEncryptedKubeconfig restores the Secret holding the encrypted Kubeconfig
+// of the user. The controller can't change encrypted Kubeconfigs, they are
+// issued again when their contexts or the public key of the user changed.
+func (h *handler) syncEncryptedKubeconfig(user *klum.User, kubeconfig *klum.Kubeconfig, namespaces []string, currentNamespace string) error {
+	contexts, current := h.contexts(namespaces, currentNamespace)
+	if user.Spec.PublicKey == "" || kubeconfig.Annotations[publicKeyHashAnnotation] != hash(user.Spec.PublicKey) ||
+		!equality.Semantic.DeepEqual(kubeconfig.Spec.Contexts, contexts) || kubeconfig.Spec.CurrentContext != current {
+		return h.reissueKubeconfig(user)
+	}
+
+	ciphertext, err := h.encryptedConfig(kubeconfig)
+	if err != nil {
+		return err
+	}
+	if len(ciphertext) == 0 {
+		return h.reissueKubeconfig(user)
+	}
+
+	var secret *v1.Secret
+	if h.cfg.KubeconfigStorage == KubeconfigStorageSecret {
+		secret, err = h.newKubeconfigSecret(kubeconfig.Name, user.Name, ciphertext)
+		if err != nil {
+			return err
+		}
+	}
+	return h.updateKubeconfigStatus(kubeconfig, secret, kubeconfig.Status)
+}
+
+// reissueKubeconfig deletes the Kubeconfig of the user so that its credential
+// is issued again, from its token Secret in CredentialModeSecret
+func (h *handler) reissueKubeconfig(user *klum.User) error {
+	if err := h.deleteKubeconfig(user.Name); err != nil {
+		return err
+	}
+	if h.cfg.CredentialMode != CredentialModeSecret {
+		return nil
+	}
+	secret, err := h.tokenSecret(user.Name)
+	if err != nil || secret == nil {
+		return err
+	}
+	h.secretController.Enqueue(secret.Namespace, secret.Name)
+	return nil
+}
+
+// applyKubeconfig applies the Kubeconfig owned by owner and records when its
+// credential was issued and expires
+func (h *handler) applyKubeconfig(owner runtime.Object, kubeconfig *klum.Kubeconfig, status klum.KubeconfigStatus) error {
+	kubeconfig, secret, err := h.kubeconfigSecret(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	err = h.apply.
+		WithOwner(owner).
+		WithSetOwnerReference(true, false).
+		ApplyObjects(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	existing, err := h.kubeconfigController.Get(kubeconfig.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return h.updateKubeconfigStatus(existing, secret, status)
+}
+
+// updateKubeconfigStatus stores the Secret of the Kubeconfig and records it in
+// the status of the Kubeconfig
+func (h *handler) updateKubeconfigStatus(kubeconfig *klum.Kubeconfig, secret *v1.Secret, status klum.KubeconfigStatus) error {
+	ref, err := h.storeKubeconfig(kubeconfig, secret)
+	if err != nil {
+		return err
+	}
+	status.SecretRef = ref
+
+	if equality.Semantic.DeepEqual(kubeconfig.Status, status) {
+		return nil
+	}
+	kubeconfig = kubeconfig.DeepCopy()
+	kubeconfig.Status = status
+	_, err = h.kubeconfigController.UpdateStatus(kubeconfig)
+	return err
+}
+
+// clusterCA returns the base64 encoded CA of the cluster from the config of
+// the controller or the root CA published in its namespace
+func (h *handler) clusterCA() (string, error) {
+	if h.cfg.CA != "" {
+		return h.cfg.CA, nil
+	}
+	cm, err := h.coreClient.ConfigMaps(h.cfg.Namespace).Get(context.TODO(), rootCAConfigMap, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString([]byte(cm.Data["ca.crt"])), nil
+}
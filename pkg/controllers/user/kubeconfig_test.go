@@ -0,0 +1,165 @@
+package user
+
+import (
+	"reflect"
+	"testing"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestContextNamespaces(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}
+	rules := []rbacv1.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}}
+
+	tests := []struct {
+		name       string
+		spec       klum.UserSpec
+		home       string
+		namespaces []string
+		current    string
+	}{
+		{
+			name: "no roles",
+		},
+		{
+			name: "cluster roles only",
+			spec: klum.UserSpec{ClusterRoles: []string{"view"}},
+		},
+		{
+			name: "cluster rules only",
+			spec: klum.UserSpec{Rules: rules},
+		},
+		{
+			name: "single namespace",
+			spec: klum.UserSpec{Roles: []klum.NamespaceRole{
+				{Namespace: "dev", ClusterRole: "edit"},
+			}},
+			namespaces: []string{"dev"},
+			current:    "dev",
+		},
+		{
+			name: "multiple namespaces in order of the roles",
+			spec: klum.UserSpec{Roles: []klum.NamespaceRole{
+				{Namespace: "staging", ClusterRole: "view"},
+				{Namespace: "dev", ClusterRole: "edit"},
+				{Namespace: "staging", Rules: rules},
+			}},
+			namespaces: []string{"staging", "dev"},
+			current:    "staging",
+		},
+		{
+			name: "cluster roles keep the cluster context current",
+			spec: klum.UserSpec{
+				ClusterRoles: []string{"view"},
+				Roles: []klum.NamespaceRole{
+					{Namespace: "dev", ClusterRole: "edit"},
+				},
+			},
+			namespaces: []string{"dev"},
+		},
+		{
+			name: "cluster role templates keep the cluster context current",
+			spec: klum.UserSpec{
+				RoleTemplates: []string{"viewer"},
+				Roles: []klum.NamespaceRole{
+					{Namespace: "dev", ClusterRole: "edit"},
+				},
+			},
+			namespaces: []string{"dev"},
+		},
+		{
+			name: "glob patterns are skipped",
+			spec: klum.UserSpec{Roles: []klum.NamespaceRole{
+				{Namespace: "team-*", ClusterRole: "edit"},
+				{Namespace: "team-?", ClusterRole: "edit"},
+				{Namespace: "team-[ab]", ClusterRole: "edit"},
+				{Namespace: "dev", ClusterRole: "edit"},
+			}},
+			namespaces: []string{"dev"},
+			current:    "dev",
+		},
+		{
+			name: "selectors are skipped",
+			spec: klum.UserSpec{Roles: []klum.NamespaceRole{
+				{NamespaceSelector: selector, ClusterRole: "edit"},
+				{Namespace: "prod", NamespaceSelector: selector, ClusterRole: "view"},
+			}},
+		},
+		{
+			name: "only patterns and selectors",
+			spec: klum.UserSpec{Roles: []klum.NamespaceRole{
+				{Namespace: "team-*", ClusterRole: "edit"},
+				{NamespaceSelector: selector, ClusterRole: "edit"},
+			}},
+		},
+		{
+			name: "default namespace",
+			spec: klum.UserSpec{
+				DefaultNamespace: "dev",
+				ClusterRoles:     []string{"view"},
+			},
+			namespaces: []string{"dev"},
+			current:    "dev",
+		},
+		{
+			name: "default namespace is current",
+			spec: klum.UserSpec{
+				DefaultNamespace: "dev",
+				Roles: []klum.NamespaceRole{
+					{Namespace: "staging", ClusterRole: "edit"},
+					{Namespace: "dev", ClusterRole: "edit"},
+				},
+			},
+			namespaces: []string{"staging", "dev"},
+			current:    "dev",
+		},
+		{
+			name:       "home namespace only",
+			home:       "darren",
+			namespaces: []string{"darren"},
+			current:    "darren",
+		},
+		{
+			name: "home namespace comes first",
+			spec: klum.UserSpec{Roles: []klum.NamespaceRole{
+				{Namespace: "dev", ClusterRole: "edit"},
+				{Namespace: "darren", ClusterRole: "view"},
+			}},
+			home:       "darren",
+			namespaces: []string{"darren", "dev"},
+			current:    "darren",
+		},
+		{
+			name: "default namespace takes precedence over the home namespace",
+			spec: klum.UserSpec{
+				DefaultNamespace: "dev",
+				Roles: []klum.NamespaceRole{
+					{Namespace: "dev", ClusterRole: "edit"},
+				},
+			},
+			home:       "darren",
+			namespaces: []string{"darren", "dev"},
+			current:    "dev",
+		},
+		{
+			name:       "cluster roles take precedence over the home namespace",
+			spec:       klum.UserSpec{ClusterRoles: []string{"view"}},
+			home:       "darren",
+			namespaces: []string{"darren"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespaces, current := contextNamespaces(tt.spec, tt.home)
+			if !reflect.DeepEqual(namespaces, tt.namespaces) {
+				t.Errorf("namespaces = %v, want %v", namespaces, tt.namespaces)
+			}
+			if current != tt.current {
+				t.Errorf("current = %q, want %q", current, tt.current)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package user
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// expandNamespaces replaces every role that uses a namespace pattern or
+// selector with one role per matching namespace.
+func (h *handler) expandNamespaces(roles []klum.NamespaceRole) ([]klum.NamespaceRole, error) {
+	var result []klum.NamespaceRole
+
+	for _, role := range roles {
+		if !matchesMany(role) {
+			result = append(result, role)
+			continue
+		}
+
+		namespaces, err := h.matchingNamespaces(role)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, namespace := range namespaces {
+			expanded := role
+			expanded.Namespace = namespace
+			expanded.NamespaceSelector = nil
+			result = append(result, expanded)
+		}
+	}
+
+	return result, nil
+}
+
+func (h *handler) matchingNamespaces(role klum.NamespaceRole) ([]string, error) {
+	selector := labels.Everything()
+	if role.NamespaceSelector != nil {
+		var err error
+		selector, err = metav1.LabelSelectorAsSelector(role.NamespaceSelector)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	namespaces, err := h.namespaces.List(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, namespace := range namespaces {
+		if namespace.DeletionTimestamp != nil || namespace.Status.Phase == v1.NamespaceTerminating {
+			continue
+		}
+		if role.Namespace != "" {
+			// an invalid pattern matches nothing
+			if ok, _ := path.Match(role.Namespace, namespace.Name); !ok {
+				continue
+			}
+		}
+		result = append(result, namespace.Name)
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
+
+func matchesMany(role klum.NamespaceRole) bool {
+	return role.NamespaceSelector != nil || strings.ContainsAny(role.Namespace, "*?[")
+}
+
+// resolveNamespace enqueues the users that have a role in the namespace or
+// matching namespaces by pattern or selector so bindings follow namespaces
+// being created, deleted or relabeled.
+func (h *handler) resolveNamespace(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	if _, ok := obj.(*v1.Namespace); !ok {
+		return nil, nil
+	}
+
+	users, err := h.users.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var result []relatedresource.Key
+	for _, user := range users {
+		spec, err := h.effectiveSpec(user)
+		if err != nil {
+			return nil, err
+		}
+		for _, role := range spec.Roles {
+			if role.Namespace == name || matchesMany(role) {
+				result = append(result, relatedresource.NewKey("", user.Name))
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,171 @@
+package user
+
+import (
+	"fmt"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// CredentialModeOIDC binds roles to the identity of users at the OIDC issuer
+// the cluster trusts and puts a login plugin for the issuer in Kubeconfigs
+const CredentialModeOIDC = "oidc"
+
+type OIDCConfig struct {
+	// IssuerURL, ClientID and ClientSecret are put in Kubeconfigs for the
+	// login plugin
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// UsernameClaim, UsernamePrefix and GroupsPrefix have to match the flags
+	// of the apiserver, only the sub and email claims are supported
+	UsernameClaim  string
+	UsernamePrefix string
+	GroupsPrefix   string
+	// AuthProvider puts the oidc auth-provider of kubectl in Kubeconfigs
+	// instead of an exec block for kubelogin
+	AuthProvider bool
+}
+
+// usernamePrefix returns the prefix the apiserver adds to usernames. Like
+// the apiserver it defaults to the issuer URL and # for claims other than
+// email, - disables it.
+func (c OIDCConfig) usernamePrefix() string {
+	switch {
+	case c.UsernamePrefix == "-":
+		return ""
+	case c.UsernamePrefix == "" && c.UsernameClaim != "email":
+		return c.IssuerURL + "#"
+	}
+	return c.UsernamePrefix
+}
+
+// oidcSubjects returns the subjects the roles of the user are bound to, the
+// user the apiserver authenticates it as and the groups of the issuer it
+// lists. Users without the claim the apiserver takes the username from have
+// none.
+func (h *handler) oidcSubjects(spec klum.UserSpec) []rbacv1.Subject {
+	username, err := h.oidcUsername(spec)
+	if err != nil {
+		return nil
+	}
+	subjects := []rbacv1.Subject{
+		{
+			Kind:     "User",
+			APIGroup: "rbac.authorization.k8s.io",
+			Name:     username,
+		},
+	}
+	for _, group := range h.oidcGroups(spec) {
+		subjects = append(subjects, rbacv1.Subject{
+			Kind:     "Group",
+			APIGroup: "rbac.authorization.k8s.io",
+			Name:     group,
+		})
+	}
+	return subjects
+}
+
+// oidcGroups returns the groups of the issuer the user lists as the apiserver
+// names them
+func (h *handler) oidcGroups(spec klum.UserSpec) []string {
+	if spec.OIDC == nil {
+		return nil
+	}
+	var groups []string
+	for _, group := range spec.OIDC.Groups {
+		groups = append(groups, h.cfg.OIDC.GroupsPrefix+group)
+	}
+	return groups
+}
+
+// oidcViolations describes why nothing can be bound to the OIDC identity of
+// the user, that is if it lacks the username claim or lists a group it may
+// not be bound to
+func (h *handler) oidcViolations(spec klum.UserSpec) ([]string, error) {
+	if h.cfg.CredentialMode != CredentialModeOIDC {
+		return nil, nil
+	}
+
+	var violations []string
+	if _, err := h.oidcUsername(spec); err != nil {
+		violations = append(violations, err.Error())
+	}
+	groupViolations, err := h.groupViolations(h.oidcGroups(spec))
+	if err != nil {
+		return nil, err
+	}
+	for _, violation := range groupViolations {
+		violations = append(violations, "oidc.groups: "+violation)
+	}
+	return violations, nil
+}
+
+// oidcUsername returns the name the apiserver authenticates the user as, the
+// user has to have the claim the apiserver takes it from
+func (h *handler) oidcUsername(spec klum.UserSpec) (string, error) {
+	if h.cfg.OIDC.UsernameClaim == "email" {
+		if spec.OIDC == nil || spec.OIDC.Email == "" {
+			return "", fmt.Errorf("oidc.email is required, the cluster authenticates users by their email")
+		}
+		return h.cfg.OIDC.usernamePrefix() + spec.OIDC.Email, nil
+	}
+	if spec.OIDC == nil || spec.OIDC.Subject == "" {
+		return "", fmt.Errorf("oidc.subject is required, the cluster authenticates users by their subject")
+	}
+	return h.cfg.OIDC.usernamePrefix() + spec.OIDC.Subject, nil
+}
+
+// ensureOIDCKubeconfig issues a Kubeconfig logging in at the OIDC issuer,
+// it holds no credential of its own
+func (h *handler) ensureOIDCKubeconfig(user *klum.User) error {
+	namespaces, currentNamespace, err := h.userNamespaces(user)
+	if err != nil {
+		return err
+	}
+	ca, err := h.clusterCA()
+	if err != nil {
+		return err
+	}
+	return h.applyKubeconfig(user, h.kubeconfig(user.Name, ca, h.oidcAuthInfo(), namespaces, currentNamespace), klum.KubeconfigStatus{})
+}
+
+func (h *handler) oidcAuthInfo() klum.AuthInfo {
+	cfg := h.cfg.OIDC
+	if cfg.AuthProvider {
+		config := map[string]string{
+			"idp-issuer-url": cfg.IssuerURL,
+			"client-id":      cfg.ClientID,
+		}
+		if cfg.ClientSecret != "" {
+			config["client-secret"] = cfg.ClientSecret
+		}
+		return klum.AuthInfo{
+			AuthProvider: &klum.AuthProviderConfig{
+				Name:   "oidc",
+				Config: config,
+			},
+		}
+	}
+
+	args := []string{
+		"oidc-login",
+		"get-token",
+		"--oidc-issuer-url=" + cfg.IssuerURL,
+		"--oidc-client-id=" + cfg.ClientID,
+	}
+	if cfg.ClientSecret != "" {
+		args = append(args, "--oidc-client-secret="+cfg.ClientSecret)
+	}
+	if cfg.UsernameClaim == "email" {
+		args = append(args, "--oidc-extra-scope=email")
+	}
+	return klum.AuthInfo{
+		Exec: &klum.ExecConfig{
+			APIVersion:  execAPIVersion,
+			Command:     "kubectl",
+			Args:        args,
+			InstallHint: "kubelogin is needed to authenticate, see https://github.com/int128/kubelogin",
+		},
+	}
+}
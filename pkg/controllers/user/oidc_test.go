@@ -0,0 +1,73 @@
+package user
+
+import (
+	"reflect"
+	"testing"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestOIDCSubjects(t *testing.T) {
+	user := func(name string) rbacv1.Subject {
+		return rbacv1.Subject{Kind: "User", APIGroup: "rbac.authorization.k8s.io", Name: name}
+	}
+	group := func(name string) rbacv1.Subject {
+		return rbacv1.Subject{Kind: "Group", APIGroup: "rbac.authorization.k8s.io", Name: name}
+	}
+
+	tests := []struct {
+		name     string
+		cfg      OIDCConfig
+		identity *klum.OIDCIdentity
+		subjects []rbacv1.Subject
+	}{
+		{
+			name:     "subject with the default prefix",
+			cfg:      OIDCConfig{IssuerURL: "https://issuer.example.com"},
+			identity: &klum.OIDCIdentity{Subject: "1234"},
+			subjects: []rbacv1.Subject{user("https://issuer.example.com#1234")},
+		},
+		{
+			name:     "email without a prefix",
+			cfg:      OIDCConfig{UsernameClaim: "email"},
+			identity: &klum.OIDCIdentity{Subject: "1234", Email: "darren@example.com"},
+			subjects: []rbacv1.Subject{user("darren@example.com")},
+		},
+		{
+			name:     "groups are bound with the user",
+			cfg:      OIDCConfig{UsernamePrefix: "-", GroupsPrefix: "oidc:"},
+			identity: &klum.OIDCIdentity{Subject: "1234", Groups: []string{"platform", "sre"}},
+			subjects: []rbacv1.Subject{user("1234"), group("oidc:platform"), group("oidc:sre")},
+		},
+		{
+			name: "no identity",
+			cfg:  OIDCConfig{UsernamePrefix: "-"},
+		},
+		{
+			name:     "missing subject",
+			cfg:      OIDCConfig{UsernamePrefix: "-"},
+			identity: &klum.OIDCIdentity{Email: "darren@example.com", Groups: []string{"platform"}},
+		},
+		{
+			name:     "missing email",
+			cfg:      OIDCConfig{UsernameClaim: "email"},
+			identity: &klum.OIDCIdentity{Subject: "1234"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &handler{cfg: Config{CredentialMode: CredentialModeOIDC, OIDC: tt.cfg}}
+			spec := klum.UserSpec{OIDC: tt.identity}
+
+			subjects := h.oidcSubjects(spec)
+			if !reflect.DeepEqual(subjects, tt.subjects) {
+				t.Errorf("subjects = %v, want %v", subjects, tt.subjects)
+			}
+			if _, err := h.oidcUsername(spec); (err != nil) != (len(tt.subjects) == 0) {
+				t.Errorf("oidcUsername() error = %v", err)
+			}
+		})
+	}
+}
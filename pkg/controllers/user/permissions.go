@@ -0,0 +1,78 @@
+package user
+
+import (
+	"sort"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	"github.com/ibuildthecloud/klum/pkg/permissions"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// getPermissions resolves the roles bound by the generated objects of a user
+// into the rules the user is granted.
+func (h *handler) getPermissions(user *klum.User, objs []runtime.Object) (*klum.UserPermissions, error) {
+	var (
+		clusterRules   []rbacv1.PolicyRule
+		namespaces     []string
+		namespaceRules = map[string][]rbacv1.PolicyRule{}
+	)
+
+	for _, obj := range objs {
+		switch binding := obj.(type) {
+		case *rbacv1.ClusterRoleBinding:
+			rules, err := h.rules(objs, "", binding.RoleRef)
+			if err != nil {
+				return nil, err
+			}
+			clusterRules = append(clusterRules, rules...)
+		case *rbacv1.RoleBinding:
+			rules, err := h.rules(objs, binding.Namespace, binding.RoleRef)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := namespaceRules[binding.Namespace]; !ok {
+				namespaces = append(namespaces, binding.Namespace)
+			}
+			namespaceRules[binding.Namespace] = append(namespaceRules[binding.Namespace], rules...)
+		}
+	}
+
+	result := &klum.UserPermissions{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: user.Name,
+		},
+		Spec: klum.UserPermissionsSpec{
+			ClusterRules: permissions.Flatten(clusterRules),
+		},
+	}
+
+	sort.Strings(namespaces)
+	for _, namespace := range namespaces {
+		result.Spec.Namespaces = append(result.Spec.Namespaces, klum.NamespacePermissions{
+			Namespace: namespace,
+			Rules:     permissions.Flatten(namespaceRules[namespace]),
+		})
+	}
+
+	return result, nil
+}
+
+// rules returns the rules of a role, roles generated for the user are taken
+// from objs as they may not have been created yet
+func (h *handler) rules(objs []runtime.Object, namespace string, ref rbacv1.RoleRef) ([]rbacv1.PolicyRule, error) {
+	for _, obj := range objs {
+		switch role := obj.(type) {
+		case *rbacv1.ClusterRole:
+			if ref.Kind == "ClusterRole" && role.Name == ref.Name {
+				return role.Rules, nil
+			}
+		case *rbacv1.Role:
+			if ref.Kind == "Role" && role.Namespace == namespace && role.Name == ref.Name {
+				return role.Rules, nil
+			}
+		}
+	}
+	return h.permissions.Rules(namespace, ref)
+}
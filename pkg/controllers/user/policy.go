@@ -0,0 +1,330 @@
+package user
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	"github.com/ibuildthecloud/klum/pkg/permissions"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// enforcePolicies removes the bindings violating a KlumPolicy from objs and
+// returns why they were removed. The roles generated from inline rules are
+// kept, they grant nothing without their binding.
+func (h *handler) enforcePolicies(objs []runtime.Object) ([]runtime.Object, []string, error) {
+	policies, err := h.policies.List(labels.Everything())
+	if err != nil || len(policies) == 0 {
+		return objs, nil, err
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Name < policies[j].Name
+	})
+
+	var (
+		result     []runtime.Object
+		violations []string
+	)
+
+	for _, obj := range objs {
+		var (
+			namespace string
+			ref       rbacv1.RoleRef
+		)
+		switch binding := obj.(type) {
+		case *rbacv1.ClusterRoleBinding:
+			ref = binding.RoleRef
+		case *rbacv1.RoleBinding:
+			namespace, ref = binding.Namespace, binding.RoleRef
+		default:
+			result = append(result, obj)
+			continue
+		}
+
+		violation := ""
+		for _, policy := range policies {
+			violation, err = h.violation(policy, objs, namespace, ref)
+			if err != nil {
+				return nil, nil, err
+			}
+			if violation != "" {
+				break
+			}
+		}
+
+		if violation == "" {
+			result = append(result, obj)
+		} else {
+			violations = append(violations, violation)
+		}
+	}
+
+	return result, violations, nil
+}
+
+// violation describes why binding the role in namespace violates the policy or
+// returns "" if it doesn't. Namespace is "" for cluster wide bindings.
+func (h *handler) violation(policy *klum.KlumPolicy, objs []runtime.Object, namespace string, ref rbacv1.RoleRef) (string, error) {
+	role := describeRef(namespace, ref)
+
+	// the names of the roles generated from inline rules are of no interest,
+	// their rules are checked against the rules of the allowed roles instead
+	if generated(objs, namespace, ref) {
+		violation, err := h.inlineViolation(policy, objs, namespace, ref)
+		if err != nil || violation != "" {
+			return violation, err
+		}
+	} else {
+		allowed, denied := policy.Spec.AllowedClusterRoles, policy.Spec.DeniedClusterRoles
+		if ref.Kind == "Role" {
+			allowed, denied = policy.Spec.AllowedRoles, policy.Spec.DeniedRoles
+		}
+
+		if matchAny(denied, ref.Name) {
+			return fmt.Sprintf("%s is denied by policy %s", role, policy.Name), nil
+		}
+		if len(allowed) > 0 && !matchAny(allowed, ref.Name) {
+			return fmt.Sprintf("%s is not allowed by policy %s", role, policy.Name), nil
+		}
+		if namespace != "" && !allowedInNamespace(policy, namespace, ref) {
+			return fmt.Sprintf("%s is not allowed in the namespace by policy %s", role, policy.Name), nil
+		}
+	}
+
+	if policy.Spec.CeilingClusterRole == "" {
+		return "", nil
+	}
+
+	// a ceiling that doesn't exist grants nothing, so nothing is below it
+	ceiling, err := h.permissions.ClusterRoleRules(policy.Spec.CeilingClusterRole)
+	if err != nil {
+		return "", err
+	}
+	rules, err := h.rules(objs, namespace, ref)
+	if err != nil {
+		return "", err
+	}
+	if ok, _ := permissions.Covers(ceiling, rules); !ok {
+		return fmt.Sprintf("%s exceeds ceiling clusterRole %s of policy %s", role,
+			policy.Spec.CeilingClusterRole, policy.Name), nil
+	}
+
+	return "", nil
+}
+
+// inlineViolation checks the rules of a role generated from inline rules. If
+// the policy allows only some roles the rules have to be within the rules of
+// those roles, and within the roles allowed in the namespace by one of the
+// namespace entries matching it.
+func (h *handler) inlineViolation(policy *klum.KlumPolicy, objs []runtime.Object, namespace string, ref rbacv1.RoleRef) (string, error) {
+	role := describeRef(namespace, ref)
+	rules, err := h.rules(objs, namespace, ref)
+	if err != nil {
+		return "", err
+	}
+
+	if len(policy.Spec.AllowedClusterRoles) > 0 || len(policy.Spec.AllowedRoles) > 0 {
+		allowed, err := h.allowedRules(policy, namespace, policy.Spec.AllowedClusterRoles, policy.Spec.AllowedRoles)
+		if err != nil {
+			return "", err
+		}
+		if ok, _ := permissions.Covers(allowed, rules); !ok {
+			return fmt.Sprintf("inline rules of %s exceed the roles allowed by policy %s", role, policy.Name), nil
+		}
+	}
+
+	if namespace == "" {
+		return "", nil
+	}
+
+	matched := false
+	for _, entry := range policy.Spec.Namespaces {
+		if !match(entry.Namespace, namespace) {
+			continue
+		}
+		matched = true
+
+		allowed, err := h.allowedRules(policy, namespace, entry.AllowedClusterRoles, entry.AllowedRoles)
+		if err != nil {
+			return "", err
+		}
+		if ok, _ := permissions.Covers(allowed, rules); ok {
+			return "", nil
+		}
+	}
+	if matched {
+		return fmt.Sprintf("inline rules of %s exceed the roles allowed in the namespace by policy %s", role, policy.Name), nil
+	}
+	return "", nil
+}
+
+// allowedRules returns the rules of the ClusterRoles, and of the Roles in the
+// namespace, matching the patterns and not denied by the policy
+func (h *handler) allowedRules(policy *klum.KlumPolicy, namespace string, clusterRolePatterns, rolePatterns []string) ([]rbacv1.PolicyRule, error) {
+	var result []rbacv1.PolicyRule
+
+	if len(clusterRolePatterns) > 0 {
+		clusterRoles, err := h.clusterRoles.List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		for _, clusterRole := range clusterRoles {
+			if !matchAny(clusterRolePatterns, clusterRole.Name) || matchAny(policy.Spec.DeniedClusterRoles, clusterRole.Name) {
+				continue
+			}
+			rules, err := h.permissions.ClusterRoleRules(clusterRole.Name)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, rules...)
+		}
+	}
+
+	if namespace != "" && len(rolePatterns) > 0 {
+		roles, err := h.roles.List(namespace, labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		for _, role := range roles {
+			if matchAny(rolePatterns, role.Name) && !matchAny(policy.Spec.DeniedRoles, role.Name) {
+				result = append(result, role.Rules...)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// groupViolations describes why the user can't be a member of the groups in
+// its client certificate, or have its roles bound to them as OIDC groups.
+func (h *handler) groupViolations(groups []string) ([]string, error) {
+	policies, err := h.policies.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Name < policies[j].Name
+	})
+	return groupViolations(policies, groups), nil
+}
+
+// groupViolations only allows groups allowed by a policy and by every other
+// policy restricting groups, groups grant whatever is bound to them. The
+// system: groups are reserved, system:masters bypasses RBAC altogether.
+func groupViolations(policies []*klum.KlumPolicy, groups []string) []string {
+	var violations []string
+	for _, group := range groups {
+		if strings.HasPrefix(group, reservedGroupPrefix) {
+			violations = append(violations, fmt.Sprintf("group %s is reserved", group))
+			continue
+		}
+
+		allowed, denied := false, false
+		for _, policy := range policies {
+			if len(policy.Spec.AllowedGroups) == 0 {
+				continue
+			}
+			if !matchAny(policy.Spec.AllowedGroups, group) {
+				violations = append(violations, fmt.Sprintf("group %s is not allowed by policy %s", group, policy.Name))
+				denied = true
+				break
+			}
+			allowed = true
+		}
+		if !allowed && !denied {
+			violations = append(violations, fmt.Sprintf("group %s is not allowed by any policy", group))
+		}
+	}
+	return violations
+}
+
+// allowedInNamespace checks the role against the namespace entries of the
+// policy matching the namespace. The role has to be allowed by one of them.
+func allowedInNamespace(policy *klum.KlumPolicy, namespace string, ref rbacv1.RoleRef) bool {
+	matched := false
+	for _, entry := range policy.Spec.Namespaces {
+		if !match(entry.Namespace, namespace) {
+			continue
+		}
+		matched = true
+
+		allowed := entry.AllowedClusterRoles
+		if ref.Kind == "Role" {
+			allowed = entry.AllowedRoles
+		}
+		if matchAny(allowed, ref.Name) {
+			return true
+		}
+	}
+	return !matched
+}
+
+// generated returns whether the role is one of the roles in objs
+func generated(objs []runtime.Object, namespace string, ref rbacv1.RoleRef) bool {
+	for _, obj := range objs {
+		switch role := obj.(type) {
+		case *rbacv1.ClusterRole:
+			if ref.Kind == "ClusterRole" && role.Name == ref.Name {
+				return true
+			}
+		case *rbacv1.Role:
+			if ref.Kind == "Role" && role.Namespace == namespace && role.Name == ref.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func describeRef(namespace string, ref rbacv1.RoleRef) string {
+	switch {
+	case ref.Kind == "Role":
+		return fmt.Sprintf("role %s/%s", namespace, ref.Name)
+	case namespace != "":
+		return fmt.Sprintf("clusterRole %s in namespace %s", ref.Name, namespace)
+	default:
+		return "clusterRole " + ref.Name
+	}
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if match(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func match(pattern, name string) bool {
+	// an invalid pattern matches nothing
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// resolvePolicy enqueues every user when a policy changes
+func (h *handler) resolvePolicy(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	if _, ok := obj.(*klum.KlumPolicy); !ok {
+		return nil, nil
+	}
+	return h.allUsers()
+}
+
+// isCeiling returns whether the ClusterRole is the ceiling of a policy
+func (h *handler) isCeiling(clusterRole string) (bool, error) {
+	policies, err := h.policies.List(labels.Everything())
+	if err != nil {
+		return false, err
+	}
+	for _, policy := range policies {
+		if policy.Spec.CeilingClusterRole == clusterRole {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,77 @@
+package user
+
+import (
+	"reflect"
+	"testing"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGroupViolations(t *testing.T) {
+	policy := func(name string, allowedGroups ...string) *klum.KlumPolicy {
+		return &klum.KlumPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       klum.KlumPolicySpec{AllowedGroups: allowedGroups},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		policies   []*klum.KlumPolicy
+		groups     []string
+		violations []string
+	}{
+		{
+			name: "no groups",
+		},
+		{
+			name:       "no policies",
+			groups:     []string{"developers"},
+			violations: []string{"group developers is not allowed by any policy"},
+		},
+		{
+			name:       "privileged group without a policy allowing it",
+			policies:   []*klum.KlumPolicy{policy("roles")},
+			groups:     []string{"kubeadm:cluster-admins"},
+			violations: []string{"group kubeadm:cluster-admins is not allowed by any policy"},
+		},
+		{
+			name:     "allowed by a policy",
+			policies: []*klum.KlumPolicy{policy("roles"), policy("groups", "developers")},
+			groups:   []string{"developers"},
+		},
+		{
+			name:     "allowed by a pattern",
+			policies: []*klum.KlumPolicy{policy("groups", "team-*")},
+			groups:   []string{"team-a", "team-b"},
+		},
+		{
+			name:       "not allowed by a policy",
+			policies:   []*klum.KlumPolicy{policy("groups", "team-*")},
+			groups:     []string{"team-a", "kubeadm:cluster-admins"},
+			violations: []string{"group kubeadm:cluster-admins is not allowed by policy groups"},
+		},
+		{
+			name:       "has to be allowed by every policy allowing groups",
+			policies:   []*klum.KlumPolicy{policy("a", "team-*"), policy("b", "team-a")},
+			groups:     []string{"team-a", "team-b"},
+			violations: []string{"group team-b is not allowed by policy b"},
+		},
+		{
+			name:       "system groups are reserved",
+			policies:   []*klum.KlumPolicy{policy("groups", "*")},
+			groups:     []string{"system:masters"},
+			violations: []string{"group system:masters is reserved"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := groupViolations(tt.policies, tt.groups)
+			if !reflect.DeepEqual(violations, tt.violations) {
+				t.Errorf("violations = %q, want %q", violations, tt.violations)
+			}
+		})
+	}
+}
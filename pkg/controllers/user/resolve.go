@@ -0,0 +1,157 @@
+package user
+
+import (
+	"fmt"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	"github.com/ibuildthecloud/klum/pkg/generated/controllers/klum.cattle.io/v1alpha1"
+	v1controller "github.com/rancher/wrangler-api/pkg/generated/controllers/core/v1"
+	rbaccontroller "github.com/rancher/wrangler-api/pkg/generated/controllers/rbac/v1"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// unresolved collects the references of a user to roles and namespaces that
+// don't exist
+type unresolved struct {
+	seen     map[string]bool
+	messages []string
+}
+
+func (u *unresolved) addf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if u.seen == nil {
+		u.seen = map[string]bool{}
+	}
+	// groups and users often grant the same roles
+	if !u.seen[msg] {
+		u.seen[msg] = true
+		u.messages = append(u.messages, msg)
+	}
+}
+
+func (u *unresolved) check(err error, format string, args ...interface{}) (bool, error) {
+	if errors.IsNotFound(err) {
+		u.addf(format, args...)
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (u *unresolved) clusterRole(cache rbaccontroller.ClusterRoleCache, name string) error {
+	_, err := cache.Get(name)
+	_, err = u.check(err, "clusterRole %s not found", name)
+	return err
+}
+
+func (u *unresolved) role(cache rbaccontroller.RoleCache, namespace, name string) error {
+	_, err := cache.Get(namespace, name)
+	_, err = u.check(err, "role %s/%s not found", namespace, name)
+	return err
+}
+
+func (u *unresolved) roleTemplate(cache v1alpha1.RoleTemplateCache, name string) error {
+	_, err := cache.Get(name)
+	_, err = u.check(err, "roleTemplate %s not found", name)
+	return err
+}
+
+func (u *unresolved) namespace(cache v1controller.NamespaceCache, name string) (bool, error) {
+	_, err := cache.Get(name)
+	return u.check(err, "skipped roles in namespace %s: namespace not found", name)
+}
+
+func describeRole(role klum.NamespaceRole) string {
+	switch {
+	case role.Role != "":
+		return "role " + role.Role
+	case role.ClusterRole != "":
+		return "clusterRole " + role.ClusterRole
+	case role.RoleTemplate != "":
+		return "roleTemplate " + role.RoleTemplate
+	default:
+		return "rules"
+	}
+}
+
+// resolveRole enqueues the users referencing or bound to a Role or ClusterRole
+// so their status and permissions follow the role being changed.
+func (h *handler) resolveRole(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	var matches func(spec klum.UserSpec) bool
+
+	switch obj.(type) {
+	case *rbacv1.ClusterRole:
+		// changing the ceiling of a policy may allow or deny roles of any user
+		if ok, err := h.isCeiling(name); err != nil || ok {
+			if err != nil {
+				return nil, err
+			}
+			return h.allUsers()
+		}
+		matches = func(spec klum.UserSpec) bool {
+			for _, clusterRole := range spec.ClusterRoles {
+				if clusterRole == name {
+					return true
+				}
+			}
+			for _, role := range spec.Roles {
+				if role.ClusterRole == name {
+					return true
+				}
+			}
+			return false
+		}
+	case *rbacv1.Role:
+		matches = func(spec klum.UserSpec) bool {
+			for _, role := range spec.Roles {
+				if role.Role == name && (role.Namespace == namespace || matchesMany(role)) {
+					return true
+				}
+			}
+			return false
+		}
+	default:
+		return nil, nil
+	}
+
+	users, err := h.users.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var result []relatedresource.Key
+	for _, user := range users {
+		spec, err := h.effectiveSpec(user)
+		if err != nil {
+			return nil, err
+		}
+		if matches(spec) || boundTo(user.Status.Bindings, namespace, name, obj) {
+			result = append(result, relatedresource.NewKey("", user.Name))
+		}
+	}
+
+	return result, nil
+}
+
+// boundTo returns whether one of the bindings refers to the role
+func boundTo(bindings []klum.Binding, namespace, name string, obj runtime.Object) bool {
+	for _, binding := range bindings {
+		if binding.RoleRef.Name != name {
+			continue
+		}
+		switch obj.(type) {
+		case *rbacv1.ClusterRole:
+			if binding.RoleRef.Kind == "ClusterRole" {
+				return true
+			}
+		case *rbacv1.Role:
+			if binding.RoleRef.Kind == "Role" && binding.Namespace == namespace {
+				return true
+			}
+		}
+	}
+	return false
+}
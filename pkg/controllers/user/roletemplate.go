@@ -0,0 +1,282 @@
+package user
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	"github.com/ibuildthecloud/klum/pkg/permissions"
+	name2 "github.com/rancher/wrangler/pkg/name"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	roleTemplateLabel         = "klum.cattle.io/role-template"
+	aggregateToTemplateLabel  = "klum.cattle.io/aggregate-to-template"
+	aggregateToClusterRoleKey = "rbac.authorization.k8s.io/aggregate-to-"
+)
+
+// roleTemplateName is the name of the ClusterRole a RoleTemplate is rendered
+// to. Names of RoleTemplates can't contain a ':' so this never conflicts with
+// roleTemplateRulesName.
+func roleTemplateName(template string) string {
+	return "klum:template:" + template
+}
+
+// roleTemplateRulesName is the name of the ClusterRole holding the rules of the
+// RoleTemplate itself, not the ones of included templates
+func roleTemplateRulesName(template string) string {
+	return "klum:template-rules:" + template
+}
+
+func labelValue(name string) string {
+	// label values are limited to 63 characters
+	return name2.SafeConcatName(name)
+}
+
+// OnRoleTemplateChange renders the template into an aggregated ClusterRole made
+// up of the rules of the template and the ClusterRoles of all included
+// templates.
+func (h *handler) OnRoleTemplateChange(key string, template *klum.RoleTemplate) (*klum.RoleTemplate, error) {
+	if template == nil {
+		return nil, nil
+	}
+
+	selectors := []metav1.LabelSelector{
+		{
+			MatchLabels: map[string]string{
+				aggregateToTemplateLabel: labelValue(template.Name),
+			},
+		},
+	}
+	for _, include := range template.Spec.Templates {
+		selectors = append(selectors, metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				roleTemplateLabel: labelValue(include),
+			},
+		})
+	}
+
+	aggregateTo, denied, err := h.aggregateTo(template)
+	if err != nil {
+		return template, err
+	}
+	if len(denied) > 0 {
+		h.recorder.Event(template, v1.EventTypeWarning, "AggregationDenied", strings.Join(denied, "; "))
+	}
+
+	labels := map[string]string{
+		roleTemplateLabel: labelValue(template.Name),
+	}
+	for _, clusterRole := range aggregateTo {
+		labels[aggregateToClusterRoleKey+clusterRole] = "true"
+	}
+
+	return template, h.apply.
+		WithOwner(template).
+		WithSetOwnerReference(true, false).
+		ApplyObjects(&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   roleTemplateName(template.Name),
+				Labels: labels,
+			},
+			AggregationRule: &rbacv1.AggregationRule{
+				ClusterRoleSelectors: selectors,
+			},
+		}, &rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: roleTemplateRulesName(template.Name),
+				Labels: map[string]string{
+					aggregateToTemplateLabel: labelValue(template.Name),
+				},
+			},
+			Rules: template.Spec.Rules,
+		})
+}
+
+// aggregateTo returns the ClusterRoles the template may add its permissions to
+// and why it may not add them to the others. Everyone bound to a ClusterRole
+// gets the permissions, so it has to be allowed by a policy and the permissions
+// have to be below the ceiling of every policy.
+func (h *handler) aggregateTo(template *klum.RoleTemplate) ([]string, []string, error) {
+	if len(template.Spec.AggregateTo) == 0 {
+		return nil, nil, nil
+	}
+
+	policies, err := h.policies.List(labels.Everything())
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Name < policies[j].Name
+	})
+
+	rules, err := h.templateRules(template.Name, map[string]bool{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exceeds := ""
+	for _, policy := range policies {
+		if policy.Spec.CeilingClusterRole == "" {
+			continue
+		}
+		ceiling, err := h.permissions.ClusterRoleRules(policy.Spec.CeilingClusterRole)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok, _ := permissions.Covers(ceiling, rules); !ok {
+			exceeds = fmt.Sprintf("the permissions of the template exceed ceiling clusterRole %s of policy %s",
+				policy.Spec.CeilingClusterRole, policy.Name)
+			break
+		}
+	}
+
+	var allowed, denied []string
+	for _, clusterRole := range template.Spec.AggregateTo {
+		switch {
+		case exceeds != "":
+			denied = append(denied, fmt.Sprintf("can not aggregate to clusterRole %s, %s", clusterRole, exceeds))
+		case !allowedAggregateTo(policies, clusterRole):
+			denied = append(denied, fmt.Sprintf("aggregating to clusterRole %s is not allowed by any policy", clusterRole))
+		default:
+			allowed = append(allowed, clusterRole)
+		}
+	}
+	return allowed, denied, nil
+}
+
+func allowedAggregateTo(policies []*klum.KlumPolicy, clusterRole string) bool {
+	for _, policy := range policies {
+		if matchAny(policy.Spec.AllowedAggregateTo, clusterRole) {
+			return true
+		}
+	}
+	return false
+}
+
+// templateRules returns the rules of the template and of all templates it
+// includes
+func (h *handler) templateRules(name string, seen map[string]bool) ([]rbacv1.PolicyRule, error) {
+	if seen[name] {
+		return nil, nil
+	}
+	seen[name] = true
+
+	template, err := h.roleTemplates.Get(name)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	rules := append([]rbacv1.PolicyRule{}, template.Spec.Rules...)
+	for _, include := range template.Spec.Templates {
+		included, err := h.templateRules(include, seen)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, included...)
+	}
+	return rules, nil
+}
+
+// resolveAggregatingTemplates enqueues the templates aggregating to other
+// ClusterRoles when a policy or ceiling changes, and the templates including a
+// template when it changes
+func (h *handler) resolveAggregatingTemplates(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	switch obj := obj.(type) {
+	case *klum.KlumPolicy:
+	case *rbacv1.ClusterRole:
+		if ceiling, err := h.isCeiling(obj.Name); err != nil || !ceiling {
+			return nil, err
+		}
+	case *klum.RoleTemplate:
+	default:
+		return nil, nil
+	}
+
+	templates, err := h.roleTemplates.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var result []relatedresource.Key
+	for _, template := range templates {
+		if len(template.Spec.AggregateTo) == 0 {
+			continue
+		}
+		if _, ok := obj.(*klum.RoleTemplate); ok && !includes(template, name) {
+			continue
+		}
+		result = append(result, relatedresource.NewKey("", template.Name))
+	}
+	return result, nil
+}
+
+func includes(template *klum.RoleTemplate, name string) bool {
+	for _, include := range template.Spec.Templates {
+		if include == name {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterScopedTemplate returns true if the template exists and can be bound
+// cluster wide
+func (h *handler) clusterScopedTemplate(name string) (bool, error) {
+	template, err := h.roleTemplates.Get(name)
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return !template.Spec.Namespaced, nil
+}
+
+// resolveRoleTemplate enqueues the users granted a template when it changes
+func (h *handler) resolveRoleTemplate(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	if _, ok := obj.(*klum.RoleTemplate); !ok {
+		return nil, nil
+	}
+
+	users, err := h.users.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var result []relatedresource.Key
+	for _, user := range users {
+		spec, err := h.effectiveSpec(user)
+		if err != nil {
+			return nil, err
+		}
+		if referencesTemplate(spec, name) {
+			result = append(result, relatedresource.NewKey("", user.Name))
+		}
+	}
+
+	return result, nil
+}
+
+func referencesTemplate(spec klum.UserSpec, template string) bool {
+	for _, name := range spec.RoleTemplates {
+		if name == template {
+			return true
+		}
+	}
+	for _, role := range spec.Roles {
+		if role.RoleTemplate == template {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// rotateReasonAnnotation on a user is recorded as the reason its credential
+// was rotated
+const rotateReasonAnnotation = "klum.cattle.io/rotate-reason"
+
+// rotateCredential revokes the credential of the user and deletes its
+// Kubeconfig when its credential generation changed, and returns whether it
+// did. Token Secrets are deleted and re-created, tokens from the TokenRequest
+// API can only be revoked by re-creating the ServiceAccount, which also
+// deletes refresh tokens.
+//
+// Client certificates can't be revoked, a new certificate is only issued for
+// a new key and the CredentialRevoked condition tells the previous one stays
+// valid until it expires. Rotating the certificate of a certificateRequest
+// for the key of the current certificate, or an OIDC credential which is
+// revoked at the issuer, is refused and the credential generation is left
+// unacknowledged.
+func (h *handler) rotateCredential(user *klum.User, status klum.UserStatus) (klum.UserStatus, bool, error) {
+	if user.Spec.CredentialGeneration == status.CredentialGeneration {
+		return status, false, nil
+	}
+
+	revoked, message := true, "the previous credential is revoked"
+	switch h.cfg.CredentialMode {
+	case CredentialModeSecret:
+		secrets, err := h.tokenSecrets(user.Name)
+		if err != nil {
+			return status, false, err
+		}
+		for _, secret := range secrets {
+			err := h.coreClient.Secrets(secret.Namespace).Delete(context.TODO(), secret.Name, metav1.DeleteOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return status, false, err
+			}
+		}
+	case CredentialModeTokenRequest, CredentialModeExec:
+		err := h.coreClient.ServiceAccounts(h.cfg.Namespace).Delete(context.TODO(), user.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return status, false, err
+		}
+	case CredentialModeCertificate, CredentialModeCA:
+		valid, refused, err := h.rotateCertificate(user)
+		if err != nil {
+			return status, false, err
+		}
+		if refused != "" {
+			return h.refuseRotation(user, status, refused), false, nil
+		}
+		if valid != "" {
+			revoked, message = false, valid
+		}
+	case CredentialModeOIDC:
+		return h.refuseRotation(user, status, "OIDC credentials are issued by the identity provider and can only be revoked there"), false, nil
+	}
+
+	if err := h.deleteKubeconfig(user.Name); err != nil {
+		return status, false, err
+	}
+
+	reason := user.Annotations[rotateReasonAnnotation]
+	status.CredentialGeneration = user.Spec.CredentialGeneration
+	status.LastRotatedAt = &metav1.Time{Time: time.Now()}
+	status.LastRotationReason = reason
+	status = setCondition(status, klum.UserCredentialRevokedCondition, revoked, message)
+	if reason == "" {
+		reason = "credential generation changed"
+	}
+	h.recorder.Eventf(user, v1.EventTypeNormal, "CredentialRotated", "credential rotated: %s", reason)
+	return status, true, nil
+}
+
+// rotateCertificate checks the client certificate of the user can be
+// rotated, which needs a new key. It returns until when the current
+// certificate stays valid, or why the rotation is refused.
+func (h *handler) rotateCertificate(user *klum.User) (string, string, error) {
+	_, _, cert, err := h.currentCertificate(user.Name)
+	if err != nil || cert == nil {
+		return "", "", err
+	}
+
+	request, err := certificateRequest(user, sortedGroups(user.Spec.Groups))
+	if err != nil {
+		return "", "invalid certificateRequest: " + err.Error(), nil
+	}
+	if request != nil && bytes.Equal(request.RawSubjectPublicKeyInfo, cert.RawSubjectPublicKeyInfo) {
+		return "", "the certificateRequest is for the key of the current client certificate, rotating it requires a certificateRequest for a new key", nil
+	}
+
+	return fmt.Sprintf("client certificates can't be revoked, the previous certificate stays valid until %s", cert.NotAfter.UTC().Format(time.RFC3339)), "", nil
+}
+
+// refuseRotation leaves the credential generation of the user unacknowledged
+// and warns the credential wasn't rotated.
+func (h *handler) refuseRotation(user *klum.User, status klum.UserStatus, message string) klum.UserStatus {
+	message = "credential not rotated: " + message
+	if klum.UserCredentialRevokedCondition.GetMessage(&klum.User{Status: status}) != message {
+		h.recorder.Event(user, v1.EventTypeWarning, "RotationRefused", message)
+	}
+	return setCondition(status, klum.UserCredentialRevokedCondition, false, message)
+}
@@ -0,0 +1,221 @@
+package user
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+type window struct {
+	days       [7]bool
+	start, end int
+}
+
+// contains returns true if t, in the location of the schedule, is in the window
+func (w window) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+
+	if w.start < w.end {
+		return w.days[today] && minute >= w.start && minute < w.end
+	}
+	// the window closes the day after it opens
+	return w.days[today] && minute >= w.start || w.days[yesterday] && minute < w.end
+}
+
+// checkSchedule records in the status whether the user is currently in one of
+// the windows of its schedule, emits an event if that changed and schedules the
+// user to be handled again at the next transition.
+func (h *handler) checkSchedule(user *klum.User, status klum.UserStatus, now time.Time) (klum.UserStatus, bool) {
+	if user.Spec.Schedule == nil {
+		status.NextScheduleTransition = nil
+		return removeCondition(status, klum.UserScheduleCondition), true
+	}
+
+	wasActive := klum.UserScheduleCondition.IsTrue(&klum.User{Status: status})
+	known := klum.UserScheduleCondition.GetStatus(&klum.User{Status: status}) != ""
+
+	active, next, err := evaluateSchedule(user.Spec.Schedule, now)
+	if err != nil {
+		// fail closed, an invalid schedule grants nothing
+		status.NextScheduleTransition = nil
+		return setCondition(status, klum.UserScheduleCondition, false, err.Error()), false
+	}
+
+	message := "outside of scheduled windows, roles revoked"
+	if active {
+		message = "inside of scheduled window, roles granted"
+	}
+	status = setCondition(status, klum.UserScheduleCondition, active, message)
+
+	status.NextScheduleTransition = nil
+	if !next.IsZero() {
+		status.NextScheduleTransition = &metav1.Time{Time: next}
+		h.userController.EnqueueAfter(user.Name, next.Sub(now)+time.Second)
+	}
+
+	if known && wasActive != active {
+		if active {
+			h.recorder.Event(user, v1.EventTypeNormal, "ScheduleStarted", message)
+		} else {
+			h.recorder.Event(user, v1.EventTypeNormal, "ScheduleEnded", message)
+		}
+	}
+
+	return status, active
+}
+
+// evaluateSchedule returns whether now is in one of the windows of the schedule
+// and the time that will change. The time is zero if it never changes.
+func evaluateSchedule(schedule *klum.Schedule, now time.Time) (bool, time.Time, error) {
+	loc := time.UTC
+	if schedule.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(schedule.Timezone)
+		if err != nil {
+			return false, time.Time{}, err
+		}
+	}
+
+	var windows []window
+	for _, w := range schedule.Windows {
+		parsed, err := parseWindow(w)
+		if err != nil {
+			return false, time.Time{}, err
+		}
+		windows = append(windows, parsed)
+	}
+
+	isActive := func(t time.Time) bool {
+		for _, w := range windows {
+			if w.contains(t) {
+				return true
+			}
+		}
+		return false
+	}
+
+	now = now.In(loc)
+	active := isActive(now)
+
+	// every window opens and closes at least once a week so the next
+	// transition, if any, is one of the boundaries in the coming eight days
+	var boundaries []time.Time
+	for day := 0; day <= 8; day++ {
+		for _, w := range windows {
+			for _, minute := range []int{w.start, w.end} {
+				t := wallClock(now.Year(), now.Month(), now.Day()+day, minute, loc)
+				if t.After(now) {
+					boundaries = append(boundaries, t)
+				}
+			}
+		}
+	}
+	sort.Slice(boundaries, func(i, j int) bool {
+		return boundaries[i].Before(boundaries[j])
+	})
+
+	for _, t := range boundaries {
+		if isActive(t) != active {
+			return active, t, nil
+		}
+	}
+
+	return active, time.Time{}, nil
+}
+
+// wallClock returns the first time of the day at which the clock shows minute
+// or later. A minute skipped by a daylight saving transition starts at the
+// transition.
+func wallClock(year int, month time.Month, day, minute int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, minute/60, minute%60, 0, 0, loc)
+	date := time.Date(year, month, day, 0, 0, 0, 0, loc).Day()
+	clock := func(t time.Time) int {
+		return t.Hour()*60 + t.Minute()
+	}
+	for clock(t) < minute && t.Day() == date {
+		t = t.Add(time.Minute)
+	}
+	for prev := t.Add(-time.Minute); clock(t) > minute && clock(prev) >= minute && prev.Day() == date; prev = t.Add(-time.Minute) {
+		t = prev
+	}
+	return t
+}
+
+func parseWindow(w klum.Window) (window, error) {
+	var (
+		result window
+		err    error
+	)
+
+	if len(w.Days) == 0 {
+		for i := range result.days {
+			result.days[i] = true
+		}
+	}
+
+	for _, days := range w.Days {
+		from, to := days, days
+		if i := strings.Index(days, "-"); i > 0 {
+			from, to = days[:i], days[i+1:]
+		}
+		start, err := parseWeekday(from)
+		if err != nil {
+			return result, err
+		}
+		end, err := parseWeekday(to)
+		if err != nil {
+			return result, err
+		}
+		for day := start; ; day = (day + 1) % 7 {
+			result.days[day] = true
+			if day == end {
+				break
+			}
+		}
+	}
+
+	result.start, err = parseMinute(w.Start)
+	if err != nil {
+		return result, err
+	}
+	result.end, err = parseMinute(w.End)
+	return result, err
+}
+
+func parseWeekday(day string) (time.Weekday, error) {
+	prefix := strings.ToLower(strings.TrimSpace(day))
+	for name, weekday := range weekdays {
+		if len(prefix) >= 3 && strings.HasPrefix(name, prefix) {
+			return weekday, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid day of week %q", day)
+}
+
+func parseMinute(value string) (int, error) {
+	if value == "" || value == "24:00" {
+		return 0, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected format 15:04", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
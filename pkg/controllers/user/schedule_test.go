@@ -0,0 +1,239 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+)
+
+func TestEvaluateSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule klum.Schedule
+		now      string
+		active   bool
+		next     string
+	}{
+		{
+			name: "inside window",
+			schedule: klum.Schedule{Windows: []klum.Window{
+				{Days: []string{"Mon-Fri"}, Start: "09:00", End: "17:00"},
+			}},
+			now:    "2026-10-14T10:00:00Z",
+			active: true,
+			next:   "2026-10-14T17:00:00Z",
+		},
+		{
+			name: "end of window is exclusive",
+			schedule: klum.Schedule{Windows: []klum.Window{
+				{Days: []string{"Mon-Fri"}, Start: "09:00", End: "17:00"},
+			}},
+			now:    "2026-10-16T17:00:00Z",
+			active: false,
+			next:   "2026-10-19T09:00:00Z",
+		},
+		{
+			name: "every day when no days are given",
+			schedule: klum.Schedule{Windows: []klum.Window{
+				{Start: "09:00", End: "17:00"},
+			}},
+			now:    "2026-10-18T08:00:00Z",
+			active: false,
+			next:   "2026-10-18T09:00:00Z",
+		},
+		{
+			name: "crossing midnight before midnight",
+			schedule: klum.Schedule{Windows: []klum.Window{
+				{Days: []string{"Mon"}, Start: "22:00", End: "06:00"},
+			}},
+			now:    "2026-10-12T23:00:00Z",
+			active: true,
+			next:   "2026-10-13T06:00:00Z",
+		},
+		{
+			name: "crossing midnight after midnight",
+			schedule: klum.Schedule{Windows: []klum.Window{
+				{Days: []string{"Mon"}, Start: "22:00", End: "06:00"},
+			}},
+			now:    "2026-10-13T05:59:00Z",
+			active: true,
+			next:   "2026-10-13T06:00:00Z",
+		},
+		{
+			name: "crossing midnight from a day not in the window",
+			schedule: klum.Schedule{Windows: []klum.Window{
+				{Days: []string{"Mon"}, Start: "22:00", End: "06:00"},
+			}},
+			now:    "2026-10-12T05:00:00Z",
+			active: false,
+			next:   "2026-10-12T22:00:00Z",
+		},
+		{
+			name: "until midnight",
+			schedule: klum.Schedule{Windows: []klum.Window{
+				{Days: []string{"Sat"}, Start: "20:00", End: "24:00"},
+			}},
+			now:    "2026-10-17T21:00:00Z",
+			active: true,
+			next:   "2026-10-18T00:00:00Z",
+		},
+		{
+			name: "wrapped day range inside",
+			schedule: klum.Schedule{Windows: []klum.Window{
+				{Days: []string{"Fri-Mon"}, Start: "09:00", End: "17:00"},
+			}},
+			now:    "2026-10-18T12:00:00Z",
+			active: true,
+			next:   "2026-10-18T17:00:00Z",
+		},
+		{
+			name: "wrapped day range outside",
+			schedule: klum.Schedule{Windows: []klum.Window{
+				{Days: []string{"Fri-Mon"}, Start: "09:00", End: "17:00"},
+			}},
+			now:    "2026-10-13T12:00:00Z",
+			active: false,
+			next:   "2026-10-16T09:00:00Z",
+		},
+		{
+			name: "adjacent windows are one transition",
+			schedule: klum.Schedule{Windows: []klum.Window{
+				{Days: []string{"Mon"}, Start: "09:00", End: "12:00"},
+				{Days: []string{"Mon"}, Start: "12:00", End: "17:00"},
+			}},
+			now:    "2026-10-12T10:00:00Z",
+			active: true,
+			next:   "2026-10-12T17:00:00Z",
+		},
+		{
+			name: "always active never changes",
+			schedule: klum.Schedule{Windows: []klum.Window{
+				{Start: "00:00", End: "24:00"},
+			}},
+			now:    "2026-10-12T10:00:00Z",
+			active: true,
+		},
+		{
+			name: "time zone",
+			schedule: klum.Schedule{Timezone: "America/Phoenix", Windows: []klum.Window{
+				{Days: []string{"Mon-Fri"}, Start: "09:00", End: "17:00"},
+			}},
+			now:    "2026-10-16T23:00:00Z",
+			active: true,
+			next:   "2026-10-17T00:00:00Z",
+		},
+		{
+			name: "spring forward between windows",
+			schedule: klum.Schedule{Timezone: "America/New_York", Windows: []klum.Window{
+				{Start: "09:00", End: "17:00"},
+			}},
+			now:    "2026-03-07T23:00:00Z",
+			active: false,
+			next:   "2026-03-08T13:00:00Z",
+		},
+		{
+			name: "spring forward skipping the end of the window",
+			schedule: klum.Schedule{Timezone: "America/New_York", Windows: []klum.Window{
+				{Days: []string{"Sun"}, Start: "01:00", End: "02:30"},
+			}},
+			now:    "2026-03-08T06:15:00Z",
+			active: true,
+			next:   "2026-03-08T07:00:00Z",
+		},
+		{
+			name: "spring forward skipping the start of the window",
+			schedule: klum.Schedule{Timezone: "America/New_York", Windows: []klum.Window{
+				{Days: []string{"Sun"}, Start: "02:30", End: "04:00"},
+			}},
+			now:    "2026-03-08T06:15:00Z",
+			active: false,
+			next:   "2026-03-08T07:00:00Z",
+		},
+		{
+			name: "fall back between windows",
+			schedule: klum.Schedule{Timezone: "America/New_York", Windows: []klum.Window{
+				{Start: "09:00", End: "17:00"},
+			}},
+			now:    "2026-10-31T22:00:00Z",
+			active: false,
+			next:   "2026-11-01T14:00:00Z",
+		},
+		{
+			name: "fall back inside window",
+			schedule: klum.Schedule{Timezone: "America/New_York", Windows: []klum.Window{
+				{Days: []string{"Sun"}, Start: "00:00", End: "03:00"},
+			}},
+			now:    "2026-11-01T05:00:00Z",
+			active: true,
+			next:   "2026-11-01T08:00:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, err := time.Parse(time.RFC3339, tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			active, next, err := evaluateSchedule(&tt.schedule, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if active != tt.active {
+				t.Errorf("active = %v, want %v", active, tt.active)
+			}
+			if tt.next == "" {
+				if !next.IsZero() {
+					t.Errorf("next = %v, want none", next.UTC())
+				}
+				return
+			}
+			want, err := time.Parse(time.RFC3339, tt.next)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !next.Equal(want) {
+				t.Errorf("next = %v, want %v", next.UTC(), want)
+			}
+		})
+	}
+}
+
+func TestEvaluateScheduleInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule klum.Schedule
+	}{
+		{
+			name:     "unknown time zone",
+			schedule: klum.Schedule{Timezone: "Mars/Olympus_Mons"},
+		},
+		{
+			name: "unknown day",
+			schedule: klum.Schedule{Windows: []klum.Window{
+				{Days: []string{"Funday"}, Start: "09:00", End: "17:00"},
+			}},
+		},
+		{
+			name: "ambiguous day",
+			schedule: klum.Schedule{Windows: []klum.Window{
+				{Days: []string{"T"}, Start: "09:00", End: "17:00"},
+			}},
+		},
+		{
+			name: "invalid time",
+			schedule: klum.Schedule{Windows: []klum.Window{
+				{Start: "9am", End: "17:00"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := evaluateSchedule(&tt.schedule, time.Now()); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package user
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CredentialModeCA puts client certificates signed by the controller with a
+// client CA trusted by the apiserver in Kubeconfigs
+const CredentialModeCA = "ca"
+
+// backdate allows for clocks of the apiserver being behind the controller
+const backdate = 5 * time.Minute
+
+// Signer signs client certificates with a client CA trusted by the apiserver
+type Signer struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+// NewSigner returns a Signer for the PEM encoded CA certificate and key
+func NewSigner(certPEM, keyPEM []byte) (*Signer, error) {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	if !cert.IsCA {
+		return nil, fmt.Errorf("certificate %s is not a CA", cert.Subject.CommonName)
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported CA key type %T", pair.PrivateKey)
+	}
+	return &Signer{
+		cert: cert,
+		key:  key,
+	}, nil
+}
+
+// Sign returns a client certificate for the public key valid until notAfter,
+// or the expiry of the CA if that is sooner. It never signs certificates for
+// the reserved system: groups.
+func (s *Signer) Sign(user string, groups []string, public crypto.PublicKey, now, notAfter time.Time) (*x509.Certificate, []byte, error) {
+	for _, group := range groups {
+		if strings.HasPrefix(group, reservedGroupPrefix) {
+			return nil, nil, fmt.Errorf("group %s is reserved", group)
+		}
+	}
+
+	// serial numbers must be unique per CA, 128 random bits make collisions
+	// practically impossible
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	if notAfter.After(s.cert.NotAfter) {
+		notAfter = s.cert.NotAfter
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   user,
+			Organization: groups,
+		},
+		NotBefore:   now.Add(-backdate),
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, s.cert, public, s.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+// ensureSignedCertificate issues a Kubeconfig with a client certificate signed
+// by the controller when the user has none, its certificate is due for
+// renewal, was signed by another CA or its groups or certificate request
+// changed.
+func (h *handler) ensureSignedCertificate(user *klum.User) error {
+	now := time.Now()
+	groups := sortedGroups(user.Spec.Groups)
+
+	if ok, err := h.checkGroups(user, groups); err != nil || !ok {
+		return err
+	}
+
+	request, err := certificateRequest(user, groups)
+	if err != nil {
+		h.recorder.Event(user, v1.EventTypeWarning, "InvalidCertificateRequest", err.Error())
+		return nil
+	}
+
+	cert, err := h.clientCertificate(user.Name, groups, request)
+	if err != nil {
+		return err
+	}
+	if cert != nil && cert.CheckSignatureFrom(h.cfg.Signer.cert) == nil {
+		if renew := renewAt(cert.NotBefore, cert.NotAfter); now.Before(renew) {
+			h.userController.EnqueueAfter(user.Name, renew.Sub(now))
+			return nil
+		}
+	}
+
+	public, keyPEM := crypto.PublicKey(nil), []byte(nil)
+	if request != nil {
+		public = request.PublicKey
+	} else {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return err
+		}
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return err
+		}
+		public = key.Public()
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	}
+	cert, certPEM, err := h.cfg.Signer.Sign(user.Name, groups, public, now, now.Add(h.cfg.CertificateLifetime))
+	if err != nil {
+		return err
+	}
+
+	namespaces, currentNamespace, err := h.userNamespaces(user)
+	if err != nil {
+		return err
+	}
+	ca, err := h.clusterCA()
+	if err != nil {
+		return err
+	}
+
+	err = h.applyKubeconfig(user, h.kubeconfig(user.Name, ca, klum.AuthInfo{
+		ClientCertificateData: base64.StdEncoding.EncodeToString(certPEM),
+		ClientKeyData:         base64.StdEncoding.EncodeToString(keyPEM),
+	}, namespaces, currentNamespace), klum.KubeconfigStatus{
+		IssuedAt:     &metav1.Time{Time: now},
+		ExpiresAt:    &metav1.Time{Time: cert.NotAfter},
+		SerialNumber: hex.EncodeToString(cert.SerialNumber.Bytes()),
+	})
+	if err != nil {
+		return err
+	}
+
+	h.userController.EnqueueAfter(user.Name, renewAt(cert.NotBefore, cert.NotAfter).Sub(now))
+	return nil
+}
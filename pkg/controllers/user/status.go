@@ -0,0 +1,124 @@
+package user
+
+import (
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// bindings lists the role bindings in the generated objects of a user
+func bindings(objs []runtime.Object) []klum.Binding {
+	var result []klum.Binding
+	for _, obj := range objs {
+		switch binding := obj.(type) {
+		case *rbacv1.ClusterRoleBinding:
+			result = append(result, klum.Binding{
+				Kind:    "ClusterRoleBinding",
+				Name:    binding.Name,
+				RoleRef: binding.RoleRef,
+			})
+		case *rbacv1.RoleBinding:
+			result = append(result, klum.Binding{
+				Kind:      "RoleBinding",
+				Name:      binding.Name,
+				Namespace: binding.Namespace,
+				RoleRef:   binding.RoleRef,
+			})
+		}
+	}
+	return result
+}
+
+// setCredentialStatus records the ServiceAccount, token Secret and Kubeconfig
+// of the user. They are cleared if the user isn't active.
+func (h *handler) setCredentialStatus(user *klum.User, status klum.UserStatus, active bool) (klum.UserStatus, error) {
+	status.ServiceAccountName = ""
+	status.TokenSecretName = ""
+	status.KubeconfigName = ""
+	status.CredentialIssuedAt = nil
+
+	if !active {
+		return status, nil
+	}
+	if h.usesServiceAccounts() {
+		status.ServiceAccountName = user.Name
+	}
+
+	if h.cfg.CredentialMode == CredentialModeSecret {
+		secret, err := h.tokenSecret(user.Name)
+		if err != nil || secret == nil {
+			return status, err
+		}
+		status.TokenSecretName = secret.Name
+		status.CredentialIssuedAt = secret.CreationTimestamp.DeepCopy()
+	}
+
+	kubeconfig, err := h.kubeconfigs.Get(user.Name)
+	if errors.IsNotFound(err) {
+		return status, nil
+	} else if err != nil {
+		return status, err
+	}
+	status.KubeconfigName = kubeconfig.Name
+	if kubeconfig.Status.IssuedAt != nil {
+		status.CredentialIssuedAt = kubeconfig.Status.IssuedAt.DeepCopy()
+	}
+
+	return status, nil
+}
+
+// tokenSecret returns the newest token Secret of the ServiceAccount of the user
+func (h *handler) tokenSecret(user string) (*v1.Secret, error) {
+	secrets, err := h.tokenSecrets(user)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *v1.Secret
+	for _, secret := range secrets {
+		if result == nil || result.CreationTimestamp.Before(&secret.CreationTimestamp) {
+			result = secret
+		}
+	}
+
+	return result, nil
+}
+
+// tokenSecrets returns the token Secrets of the ServiceAccount of the user
+func (h *handler) tokenSecrets(user string) ([]*v1.Secret, error) {
+	sa, err := h.serviceAccounts.Get(h.cfg.Namespace, user)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	secrets, err := h.secrets.List(h.cfg.Namespace, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*v1.Secret
+	for _, secret := range secrets {
+		if secret.Type == v1.SecretTypeServiceAccountToken &&
+			secret.Annotations["kubernetes.io/service-account.name"] == sa.Name &&
+			types.UID(secret.Annotations["kubernetes.io/service-account.uid"]) == sa.UID {
+			result = append(result, secret)
+		}
+	}
+
+	return result, nil
+}
+
+// resolveKubeconfig enqueues the user of a Kubeconfig, which has the same name
+func resolveKubeconfig(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	if _, ok := obj.(*klum.Kubeconfig); ok {
+		return []relatedresource.Key{relatedresource.NewKey("", name)}, nil
+	}
+	return nil, nil
+}
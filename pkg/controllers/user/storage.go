@@ -0,0 +1,272 @@
+package user
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	"github.com/ibuildthecloud/klum/pkg/encryption"
+	name2 "github.com/rancher/wrangler/pkg/name"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// KubeconfigStorageResource stores Kubeconfigs with their credentials in
+	// the Kubeconfig resource
+	KubeconfigStorageResource = "resource"
+	// KubeconfigStorageSecret stores Kubeconfigs with their credentials in a
+	// Secret, the Kubeconfig resource only references it
+	KubeconfigStorageSecret = "secret"
+
+	// KubeconfigSecretType is the type of the Secrets holding Kubeconfigs
+	KubeconfigSecretType v1.SecretType = "klum.cattle.io/kubeconfig"
+	// KubeconfigSecretKey is the key of the Kubeconfig in its Secret
+	KubeconfigSecretKey = "config"
+
+	publicKeyHashAnnotation = "klum.cattle.io/public-key-hash"
+	configHashAnnotation    = "klum.cattle.io/config-hash"
+)
+
+// kubeconfigSecret returns the Kubeconfig to store in the Kubeconfig resource
+// and, with KubeconfigStorageSecret, the Secret holding the Kubeconfig. The
+// credentials are removed from the resource when the Kubeconfig is stored in
+// the Secret or encrypted to the public key of the user.
+func (h *handler) kubeconfigSecret(kubeconfig *klum.Kubeconfig) (*klum.Kubeconfig, *v1.Secret, error) {
+	user := kubeconfig.Labels["klum.cattle.io/user"]
+	publicKey, err := h.publicKey(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	if h.cfg.KubeconfigStorage != KubeconfigStorageSecret && publicKey == "" {
+		return kubeconfig, nil, nil
+	}
+
+	data, err := kubeconfigData(kubeconfig.Spec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stored := kubeconfig.DeepCopy()
+	for i, authInfo := range stored.Spec.AuthInfos {
+		// certificates aren't secret and needed to renew them
+		stored.Spec.AuthInfos[i].AuthInfo = klum.AuthInfo{
+			ClientCertificateData: authInfo.AuthInfo.ClientCertificateData,
+		}
+	}
+	if publicKey != "" {
+		if data, err = h.encryptKubeconfig(stored, data, publicKey); err != nil {
+			return nil, nil, fmt.Errorf("encrypting kubeconfig %s: %v", kubeconfig.Name, err)
+		}
+	}
+
+	if h.cfg.KubeconfigStorage != KubeconfigStorageSecret {
+		stored.Spec.EncryptedConfig = string(data)
+		return stored, nil, nil
+	}
+
+	secret, err := h.newKubeconfigSecret(kubeconfig.Name, user, data)
+	return stored, secret, err
+}
+
+// kubeconfigData returns the Kubeconfig file of the spec, with the apiVersion
+// and kind kubectl expects
+func kubeconfigData(spec klum.KubeconfigSpec) ([]byte, error) {
+	spec.EncryptedConfig = ""
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, err
+	}
+	return clientcmd.Write(*config)
+}
+
+// newKubeconfigSecret returns the Secret holding the Kubeconfig data
+func (h *handler) newKubeconfigSecret(name, user string, data []byte) (*v1.Secret, error) {
+	namespace, err := h.kubeconfigSecretNamespace(user)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name2.SafeConcatName(name, "kubeconfig"),
+			Namespace: namespace,
+			Labels: map[string]string{
+				"klum.cattle.io/user":       user,
+				"klum.cattle.io/kubeconfig": name,
+			},
+		},
+		Type: KubeconfigSecretType,
+		Data: map[string][]byte{
+			KubeconfigSecretKey: data,
+		},
+	}, nil
+}
+
+// encryptKubeconfig encrypts the Kubeconfig data to the public key and records
+// their hashes in the annotations of the Kubeconfig. Encryption is randomized,
+// the ciphertext of the stored Kubeconfig is reused while the hashes match, so
+// that it isn't updated every time it is applied.
+func (h *handler) encryptKubeconfig(kubeconfig *klum.Kubeconfig, data []byte, publicKey string) ([]byte, error) {
+	configHash := hash(publicKey, string(data))
+	if kubeconfig.Annotations == nil {
+		kubeconfig.Annotations = map[string]string{}
+	}
+	kubeconfig.Annotations[publicKeyHashAnnotation] = hash(publicKey)
+	kubeconfig.Annotations[configHashAnnotation] = configHash
+
+	existing, err := h.kubeconfigs.Get(kubeconfig.Name)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	} else if err == nil && existing.Annotations[configHashAnnotation] == configHash {
+		ciphertext, err := h.encryptedConfig(existing)
+		if err != nil || len(ciphertext) > 0 {
+			return ciphertext, err
+		}
+	}
+
+	recipient, err := encryption.ParseRecipient(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return recipient.Encrypt(data)
+}
+
+// encryptedConfig returns the encrypted Kubeconfig stored in the Kubeconfig
+// resource or its Secret
+func (h *handler) encryptedConfig(kubeconfig *klum.Kubeconfig) ([]byte, error) {
+	if kubeconfig.Spec.EncryptedConfig != "" {
+		return []byte(kubeconfig.Spec.EncryptedConfig), nil
+	}
+	ref := kubeconfig.Status.SecretRef
+	if ref == nil {
+		return nil, nil
+	}
+	secret, err := h.secrets.Get(ref.Namespace, ref.Name)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return secret.Data[KubeconfigSecretKey], nil
+}
+
+// encrypted returns whether the Kubeconfig is encrypted, the controller can't
+// read its credentials then
+func encrypted(kubeconfig *klum.Kubeconfig) bool {
+	return kubeconfig.Annotations[publicKeyHashAnnotation] != ""
+}
+
+// publicKey returns the public key of the user the Kubeconfig is encrypted to
+func (h *handler) publicKey(userName string) (string, error) {
+	user, err := h.users.Get(userName)
+	if errors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return user.Spec.PublicKey, nil
+}
+
+// hash returns the hex encoded SHA-256 of the values
+func hash(values ...string) string {
+	h := sha256.New()
+	for _, value := range values {
+		h.Write([]byte(value))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// kubeconfigSecretNamespace returns the namespace of the Secret of a Kubeconfig
+// of the user. That is the namespace of the controller, unless Secrets are
+// stored in home namespaces and the home namespace of the user exists.
+func (h *handler) kubeconfigSecretNamespace(userName string) (string, error) {
+	if !h.cfg.HomeNamespaceSecrets {
+		return h.cfg.Namespace, nil
+	}
+
+	user, err := h.users.Get(userName)
+	if errors.IsNotFound(err) {
+		return h.cfg.Namespace, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	namespace := h.homeNamespace(user)
+	if namespace == "" {
+		return h.cfg.Namespace, nil
+	}
+	// the home namespace is created after the credential of a new user is
+	// issued, the Secret is moved once it exists
+	if _, err := h.namespaces.Get(namespace); errors.IsNotFound(err) {
+		return h.cfg.Namespace, nil
+	} else if err != nil {
+		return "", err
+	}
+	return namespace, nil
+}
+
+// storeKubeconfig applies the Secret of the Kubeconfig, owned by the
+// Kubeconfig, and returns a reference to it. Without a Secret the Secret
+// stored before is deleted.
+func (h *handler) storeKubeconfig(kubeconfig *klum.Kubeconfig, secret *v1.Secret) (*v1.SecretReference, error) {
+	if secret == nil {
+		if kubeconfig.Status.SecretRef == nil {
+			return nil, nil
+		}
+		return nil, h.apply.WithOwner(kubeconfig).ApplyObjects()
+	}
+
+	err := h.apply.
+		WithOwner(kubeconfig).
+		WithSetOwnerReference(true, false).
+		ApplyObjects(secret)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.SecretReference{
+		Name:      secret.Name,
+		Namespace: secret.Namespace,
+	}, nil
+}
+
+// kubeconfigSpec returns the spec of the Kubeconfig with its credentials, read
+// from its Secret if it has one. The credentials of encrypted Kubeconfigs
+// can't be read.
+func (h *handler) kubeconfigSpec(kubeconfig *klum.Kubeconfig) (klum.KubeconfigSpec, error) {
+	ref := kubeconfig.Status.SecretRef
+	if ref == nil || encrypted(kubeconfig) {
+		return kubeconfig.Spec, nil
+	}
+
+	secret, err := h.secrets.Get(ref.Namespace, ref.Name)
+	if errors.IsNotFound(err) {
+		return kubeconfig.Spec, nil
+	} else if err != nil {
+		return klum.KubeconfigSpec{}, err
+	}
+
+	var spec klum.KubeconfigSpec
+	err = yaml.Unmarshal(secret.Data[KubeconfigSecretKey], &spec)
+	return spec, err
+}
+
+// resolveKubeconfigSecret enqueues the user of a Kubeconfig Secret, so that it
+// is restored when changed or deleted
+func resolveKubeconfigSecret(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	if secret, ok := obj.(*v1.Secret); ok && secret.Type == KubeconfigSecretType && secret.Labels["klum.cattle.io/user"] != "" {
+		return []relatedresource.Key{relatedresource.NewKey("", secret.Labels["klum.cattle.io/user"])}, nil
+	}
+	return nil, nil
+}
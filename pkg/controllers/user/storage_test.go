@@ -0,0 +1,76 @@
+package user
+
+import (
+	"reflect"
+	"testing"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+)
+
+func TestKubeconfigData(t *testing.T) {
+	spec := klum.KubeconfigSpec{
+		Clusters: []klum.NamedCluster{
+			{
+				Name: "cluster",
+				Cluster: klum.Cluster{
+					Server:                   "https://10.0.0.1:6443",
+					CertificateAuthorityData: "Y2E=",
+				},
+			},
+		},
+		AuthInfos: []klum.NamedAuthInfo{
+			{
+				Name:     "darren",
+				AuthInfo: klum.AuthInfo{Token: "token"},
+			},
+		},
+		Contexts: []klum.NamedContext{
+			{
+				Name: "default",
+				Context: klum.Context{
+					Cluster:   "cluster",
+					AuthInfo:  "darren",
+					Namespace: "dev",
+				},
+			},
+		},
+		CurrentContext:  "default",
+		EncryptedConfig: "left out",
+	}
+
+	data, err := kubeconfigData(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var typeMeta struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+	}
+	if err := yaml.Unmarshal(data, &typeMeta); err != nil {
+		t.Fatal(err)
+	}
+	if typeMeta.APIVersion != "v1" || typeMeta.Kind != "Config" {
+		t.Errorf("apiVersion = %q, kind = %q, want v1 Config", typeMeta.APIVersion, typeMeta.Kind)
+	}
+
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.AuthInfos["darren"].Token != "token" || string(config.Clusters["cluster"].CertificateAuthorityData) != "ca" {
+		t.Errorf("kubeconfig lost its credentials:\n%s", data)
+	}
+
+	// the controller reads the credentials back from the Secret
+	var read klum.KubeconfigSpec
+	if err := yaml.Unmarshal(data, &read); err != nil {
+		t.Fatal(err)
+	}
+	spec.EncryptedConfig = ""
+	if !reflect.DeepEqual(read, spec) {
+		t.Errorf("read back %+v, want %+v", read, spec)
+	}
+}
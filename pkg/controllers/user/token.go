@@ -0,0 +1,158 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	name2 "github.com/rancher/wrangler/pkg/name"
+	"github.com/rancher/wrangler/pkg/relatedresource"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+)
+
+const (
+	// CredentialModeSecret puts the token of the ServiceAccount token Secret in Kubeconfigs
+	CredentialModeSecret = "secret"
+	// CredentialModeTokenRequest puts expiring tokens issued by the TokenRequest API in Kubeconfigs
+	CredentialModeTokenRequest = "token-request"
+)
+
+// noTokenSecretsVersion is the first version of Kubernetes that doesn't create
+// a token Secret for every ServiceAccount
+var noTokenSecretsVersion = version.MustParseGeneric("1.24.0")
+
+// CreatesTokenSecrets returns whether the cluster creates a token Secret for
+// every ServiceAccount
+func CreatesTokenSecrets(discovery discovery.ServerVersionInterface) (bool, error) {
+	info, err := discovery.ServerVersion()
+	if err != nil {
+		return false, err
+	}
+	v, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return false, err
+	}
+	return !v.AtLeast(noTokenSecretsVersion), nil
+}
+
+// getTokenSecret returns the token Secret of the ServiceAccount of the user
+// for clusters that don't create one. The ServiceAccount has to exist first,
+// otherwise Kubernetes deletes the Secret.
+func (h *handler) getTokenSecret(user string) ([]runtime.Object, error) {
+	if h.cfg.CredentialMode != CredentialModeSecret || h.cfg.TokenSecretsCreated {
+		return nil, nil
+	}
+
+	sa, err := h.serviceAccounts.Get(h.cfg.Namespace, user)
+	if errors.IsNotFound(err) {
+		// the user is enqueued again once the ServiceAccount is created
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return []runtime.Object{
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name2.SafeConcatName(user, "token"),
+				Namespace: h.cfg.Namespace,
+				Annotations: map[string]string{
+					"klum.cattle.io/user":                user,
+					"kubernetes.io/service-account.name": sa.Name,
+					"kubernetes.io/service-account.uid":  string(sa.UID),
+				},
+			},
+			Type: v1.SecretTypeServiceAccountToken,
+		},
+	}, nil
+}
+
+// renewFraction is the part of the lifetime of a credential after which it is
+// re-issued
+const renewFraction = 0.8
+
+// renewAt returns when a credential issued and expiring at the given times is
+// re-issued
+func renewAt(issued, expires time.Time) time.Time {
+	return issued.Add(time.Duration(float64(expires.Sub(issued)) * renewFraction))
+}
+
+// ensureToken issues a Kubeconfig with a token from the TokenRequest API when
+// the user has none or its token is due for renewal, and requeues the user
+// for the next renewal.
+func (h *handler) ensureToken(user *klum.User) error {
+	sa, err := h.serviceAccounts.Get(h.cfg.Namespace, user.Name)
+	if errors.IsNotFound(err) {
+		// the user is enqueued again once the ServiceAccount is created
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	kubeconfig, err := h.kubeconfigs.Get(user.Name)
+	if err == nil && kubeconfig.Status.IssuedAt != nil && kubeconfig.Status.ExpiresAt != nil {
+		renew := renewAt(kubeconfig.Status.IssuedAt.Time, kubeconfig.Status.ExpiresAt.Time)
+		if now.Before(renew) {
+			h.userController.EnqueueAfter(user.Name, renew.Sub(now))
+			return nil
+		}
+	} else if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	token, err := h.requestToken(sa.Name)
+	if err != nil {
+		return err
+	}
+
+	namespaces, currentNamespace, err := h.userNamespaces(user)
+	if err != nil {
+		return err
+	}
+	ca, err := h.clusterCA()
+	if err != nil {
+		return err
+	}
+
+	// the apiserver may issue tokens with a different lifetime than requested
+	expires := token.Status.ExpirationTimestamp
+	err = h.applyKubeconfig(sa, h.kubeconfig(user.Name, ca, klum.AuthInfo{
+		Token: token.Status.Token,
+	}, namespaces, currentNamespace), klum.KubeconfigStatus{
+		IssuedAt:  &metav1.Time{Time: now},
+		ExpiresAt: &expires,
+	})
+	if err != nil {
+		return err
+	}
+
+	h.userController.EnqueueAfter(user.Name, renewAt(now, expires.Time).Sub(now))
+	return nil
+}
+
+// requestToken issues a token of the ServiceAccount with the configured
+// lifetime and audiences
+func (h *handler) requestToken(serviceAccount string) (*authenticationv1.TokenRequest, error) {
+	seconds := int64(h.cfg.TokenLifetime / time.Second)
+	return h.coreClient.ServiceAccounts(h.cfg.Namespace).CreateToken(context.TODO(), serviceAccount, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences:         h.cfg.TokenAudiences,
+			ExpirationSeconds: &seconds,
+		},
+	}, metav1.CreateOptions{})
+}
+
+// resolveServiceAccount enqueues the user of a ServiceAccount
+func resolveServiceAccount(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	if sa, ok := obj.(*v1.ServiceAccount); ok && sa.Annotations["klum.cattle.io/user"] != "" {
+		return []relatedresource.Key{relatedresource.NewKey("", sa.Annotations["klum.cattle.io/user"])}, nil
+	}
+	return nil, nil
+}
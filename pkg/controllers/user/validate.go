@@ -0,0 +1,207 @@
+package user
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	"github.com/ibuildthecloud/klum/pkg/encryption"
+	"github.com/ibuildthecloud/klum/pkg/generated/controllers/klum.cattle.io/v1alpha1"
+	"github.com/ibuildthecloud/klum/pkg/permissions"
+	v1controller "github.com/rancher/wrangler-api/pkg/generated/controllers/core/v1"
+	rbaccontroller "github.com/rancher/wrangler-api/pkg/generated/controllers/rbac/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Validator checks Users and Credentials before they are admitted, it reports
+// what the controller would otherwise skip
+type Validator struct {
+	h *handler
+}
+
+func NewValidator(cfg Config,
+	user v1alpha1.UserController,
+	clusterRole rbaccontroller.ClusterRoleController,
+	role rbaccontroller.RoleController,
+	namespace v1controller.NamespaceController,
+	roleTemplate v1alpha1.RoleTemplateController,
+	policy v1alpha1.KlumPolicyController) *Validator {
+	return &Validator{
+		h: &handler{
+			cfg:           cfg,
+			users:         user.Cache(),
+			namespaces:    namespace.Cache(),
+			clusterRoles:  clusterRole.Cache(),
+			roles:         role.Cache(),
+			roleTemplates: roleTemplate.Cache(),
+			permissions:   permissions.NewResolver(clusterRole.Cache(), role.Cache()),
+			policies:      policy.Cache(),
+		},
+	}
+}
+
+// Validate returns everything that is wrong with the user. The roles granted
+// through groups and access requests are not checked.
+func (v *Validator) Validate(user *klum.User) ([]string, error) {
+	var problems []string
+
+	if reservedUserName(user.Name) {
+		problems = append(problems, fmt.Sprintf("name: names starting with %s- are reserved for credentials", credentialPrefix))
+	}
+
+	// the name of the user is the name of its ServiceAccount
+	for _, msg := range validation.IsDNS1123Subdomain(user.Name) {
+		problems = append(problems, "invalid name: "+msg)
+	}
+
+	problems = append(problems, validateRoles(user.Spec.Roles)...)
+
+	if user.Spec.Schedule != nil {
+		if _, _, err := evaluateSchedule(user.Spec.Schedule, time.Now()); err != nil {
+			problems = append(problems, "invalid schedule: "+err.Error())
+		}
+	}
+
+	if namespace := v.h.homeNamespaceName(user); namespace != "" {
+		if err := v.h.checkHomeNamespace(user, namespace); err != nil {
+			problems = append(problems, "homeNamespace: "+err.Error())
+		}
+	}
+
+	groupViolations, err := v.h.groupViolations(user.Spec.Groups)
+	if err != nil {
+		return nil, err
+	}
+	for _, violation := range groupViolations {
+		problems = append(problems, "groups: "+violation)
+	}
+
+	if user.Spec.CertificateRequest != "" {
+		if v.h.cfg.CredentialMode != CredentialModeCertificate && v.h.cfg.CredentialMode != CredentialModeCA {
+			problems = append(problems, "certificateRequest: klum doesn't issue client certificates in credential mode "+v.h.cfg.CredentialMode)
+		} else if _, err := certificateRequest(user, sortedGroups(user.Spec.Groups)); err != nil {
+			problems = append(problems, "invalid certificateRequest: "+err.Error())
+		}
+	}
+
+	if user.Spec.PublicKey != "" {
+		if _, err := encryption.ParseRecipient(user.Spec.PublicKey); err != nil {
+			problems = append(problems, "invalid publicKey: "+err.Error())
+		}
+	}
+
+	oidcViolations, err := v.h.oidcViolations(user.Spec)
+	if err != nil {
+		return nil, err
+	}
+	problems = append(problems, oidcViolations...)
+
+	objs, _, err := v.h.getRoles(user.Name, user.Spec, v.h.subjects(user.Name, user.Spec))
+	if err != nil {
+		return nil, err
+	}
+	objs = append(objs, v.h.getHomeBinding(user)...)
+	_, violations, err := v.h.enforcePolicies(objs)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(problems, violations...), nil
+}
+
+// ValidateCredential returns everything that is wrong with the credential.
+// Whether its user has the roles of the credential is left to the controller,
+// which knows the roles granted through groups and access requests.
+func (v *Validator) ValidateCredential(cred *klum.Credential) ([]string, error) {
+	var problems []string
+
+	for _, msg := range validation.IsDNS1123Subdomain(cred.Name) {
+		problems = append(problems, "invalid name: "+msg)
+	}
+	if cred.Spec.User == "" {
+		problems = append(problems, "user is required")
+	}
+	if cred.Spec.TTL != nil && cred.Spec.TTL.Duration <= 0 {
+		problems = append(problems, "ttl must be positive")
+	}
+	problems = append(problems, validateRoles(cred.Spec.Roles)...)
+
+	name := credentialName(cred)
+	spec := klum.UserSpec{
+		ClusterRoles: cred.Spec.ClusterRoles,
+		Roles:        cred.Spec.Roles,
+	}
+	objs, _, err := v.h.getRoles(name, spec, []rbacv1.Subject{
+		{
+			Kind:      "ServiceAccount",
+			Name:      name,
+			Namespace: v.h.cfg.Namespace,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, violations, err := v.h.enforcePolicies(objs)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(problems, violations...), nil
+}
+
+func validateRoles(roles []klum.NamespaceRole) []string {
+	var problems []string
+	for i, role := range roles {
+		if role.Role == "" && role.ClusterRole == "" && role.RoleTemplate == "" && len(role.Rules) == 0 {
+			problems = append(problems, fmt.Sprintf("roles[%d]: one of role, clusterRole, roleTemplate or rules is required", i))
+		}
+		if role.Namespace == "" && role.NamespaceSelector == nil {
+			problems = append(problems, fmt.Sprintf("roles[%d]: namespace or namespaceSelector is required", i))
+		}
+		if _, err := path.Match(role.Namespace, ""); err != nil {
+			problems = append(problems, fmt.Sprintf("roles[%d]: invalid namespace pattern %q", i, role.Namespace))
+		}
+		if role.NamespaceSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(role.NamespaceSelector); err != nil {
+				problems = append(problems, fmt.Sprintf("roles[%d]: invalid namespaceSelector: %v", i, err))
+			}
+		}
+	}
+	return problems
+}
+
+// ValidateApprover returns what is wrong with the approver of the request, who
+// has to set approvedBy to the name of the User it authenticates as, or to its
+// own username, and can't be the user requesting access.
+func (v *Validator) ValidateApprover(req *klum.AccessRequest, approver authenticationv1.UserInfo) ([]string, error) {
+	var problems []string
+
+	if req.Status.ApprovedBy != approver.Username {
+		user, err := v.h.users.Get(req.Status.ApprovedBy)
+		if errors.IsNotFound(err) {
+			problems = append(problems, fmt.Sprintf("approvedBy: %s is neither a user nor %s approving the request",
+				req.Status.ApprovedBy, approver.Username))
+		} else if err != nil {
+			return nil, err
+		} else if v.h.username(user.Name, user.Spec) != approver.Username {
+			problems = append(problems, fmt.Sprintf("approvedBy: %s doesn't authenticate as %s approving the request",
+				req.Status.ApprovedBy, approver.Username))
+		}
+	}
+
+	requester, err := v.h.users.Get(req.Spec.User)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if req.Status.ApprovedBy == req.Spec.User ||
+		err == nil && v.h.username(requester.Name, requester.Spec) == approver.Username {
+		problems = append(problems, fmt.Sprintf("user %s can not approve its own request", req.Spec.User))
+	}
+
+	return problems, nil
+}
@@ -18,8 +18,48 @@ func Create(ctx context.Context, config *rest.Config) error {
 	}
 
 	return factory.BatchCreateCRDs(ctx,
-		newCRD("User.klum.cattle.io/v1alpha1", v1alpha1.User{}),
-		newCRD("Kubeconfig.klum.cattle.io/v1alpha1", v1alpha1.Kubeconfig{})).BatchWait()
+		newCRD("User.klum.cattle.io/v1alpha1", v1alpha1.User{}).
+			WithColumn("Ready", `.status.conditions[?(@.type=="Ready")].status`).
+			WithColumn("Expires In", ".status.expiresIn").
+			WithColumn("Kubeconfig", ".status.kubeconfigName").
+			WithCustomColumn(dateColumn("Issued", ".status.credentialIssuedAt"), age),
+		newCRD("Kubeconfig.klum.cattle.io/v1alpha1", v1alpha1.Kubeconfig{}).
+			WithColumn("Server", ".spec.clusters[0].cluster.server").
+			WithColumn("Secret", ".status.secretRef.name").
+			WithCustomColumn(dateColumn("Expires", ".status.expiresAt"), age),
+		newCRD("Group.klum.cattle.io/v1alpha1", v1alpha1.Group{}).
+			WithColumn("Members", ".spec.members").
+			WithCustomColumn(age),
+		newCRD("AccessRequest.klum.cattle.io/v1alpha1", v1alpha1.AccessRequest{}).
+			WithColumn("User", ".spec.user").
+			WithColumn("State", ".status.state").
+			WithColumn("Approved By", ".status.approvedBy").
+			WithCustomColumn(dateColumn("Expires", ".status.expiresAt"), age),
+		newCRD("RoleTemplate.klum.cattle.io/v1alpha1", v1alpha1.RoleTemplate{}).
+			WithColumn("Namespaced", ".spec.namespaced").
+			WithCustomColumn(age),
+		newCRD("UserPermissions.klum.cattle.io/v1alpha1", v1alpha1.UserPermissions{}).
+			WithCustomColumn(age),
+		newCRD("KlumPolicy.klum.cattle.io/v1alpha1", v1alpha1.KlumPolicy{}).
+			WithColumn("Ceiling", ".spec.ceilingClusterRole").
+			WithCustomColumn(age),
+		newCRD("Credential.klum.cattle.io/v1alpha1", v1alpha1.Credential{}).
+			WithColumn("User", ".spec.user").
+			WithColumn("Ready", `.status.conditions[?(@.type=="Ready")].status`).
+			WithColumn("Description", ".spec.description").
+			WithCustomColumn(dateColumn("Expires", ".status.expiresAt"), age)).BatchWait()
+}
+
+// age is the column kubectl shows by default, it has to be added explicitly once
+// there are other columns
+var age = dateColumn("Age", ".metadata.creationTimestamp")
+
+func dateColumn(name, path string) v1.CustomResourceColumnDefinition {
+	return v1.CustomResourceColumnDefinition{
+		Name:     name,
+		Type:     "date",
+		JSONPath: path,
+	}
 }
 
 func newCRD(name string, obj interface{}) crd.CRD {
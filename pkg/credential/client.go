@@ -0,0 +1,76 @@
+package credential
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientauthenticationv1beta1 "k8s.io/client-go/pkg/apis/clientauthentication/v1beta1"
+)
+
+// Request fetches a token for the user from the credential server at the URL,
+// trusting the CA in caFile in addition to the system roots if given
+func Request(url, name, refreshToken, caFile string) (*Token, error) {
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+	if caFile != "" {
+		caPEM, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		client.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs: pool,
+			},
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(url, "/")+tokenPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(name, refreshToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("credential server responded %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	token := &Token{}
+	return token, json.NewDecoder(resp.Body).Decode(token)
+}
+
+// ExecCredential returns the token as the output of an exec plugin
+func ExecCredential(token *Token) *clientauthenticationv1beta1.ExecCredential {
+	expiration := token.ExpirationTimestamp
+	return &clientauthenticationv1beta1.ExecCredential{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: clientauthenticationv1beta1.SchemeGroupVersion.String(),
+			Kind:       "ExecCredential",
+		},
+		Status: &clientauthenticationv1beta1.ExecCredentialStatus{
+			Token:               token.Token,
+			ExpirationTimestamp: &expiration,
+		},
+	}
+}
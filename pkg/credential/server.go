@@ -0,0 +1,165 @@
+package credential
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ibuildthecloud/klum/pkg/controllers/user"
+	v1controller "github.com/rancher/wrangler-api/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const tokenPath = "/token"
+
+var errUnauthorized = fmt.Errorf("unauthorized")
+
+type Config struct {
+	// Listen is the address to serve on
+	Listen string
+	// CertFile and KeyFile are the serving certificate
+	CertFile string
+	KeyFile  string
+	// Insecure serves plain HTTP without a serving certificate, for servers
+	// behind a proxy terminating TLS
+	Insecure bool
+	// Namespace holds the ServiceAccounts and refresh tokens of the users
+	Namespace string
+	// TokenLifetime and TokenAudiences are requested for the tokens issued
+	TokenLifetime  time.Duration
+	TokenAudiences []string
+}
+
+// Token is the response of the credential server
+type Token struct {
+	Token               string      `json:"token"`
+	ExpirationTimestamp metav1.Time `json:"expirationTimestamp"`
+}
+
+type server struct {
+	cfg             Config
+	coreClient      corev1.CoreV1Interface
+	serviceAccounts v1controller.ServiceAccountCache
+	secrets         v1controller.SecretCache
+}
+
+// Run serves tokens of the ServiceAccounts of users authenticating with their
+// name and refresh token until the context is done. Refresh tokens are only
+// served over plain HTTP if the config is explicitly insecure.
+func Run(ctx context.Context, cfg Config, coreClient corev1.CoreV1Interface,
+	serviceAccounts v1controller.ServiceAccountCache, secrets v1controller.SecretCache) error {
+	if (cfg.CertFile == "" || cfg.KeyFile == "") && !cfg.Insecure {
+		return fmt.Errorf("the credential server needs a serving certificate and key to not serve refresh tokens over plain HTTP")
+	}
+
+	s := &server{
+		cfg:             cfg,
+		coreClient:      coreClient,
+		serviceAccounts: serviceAccounts,
+		secrets:         secrets,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(tokenPath, s.serveToken)
+
+	srv := &http.Server{
+		Addr:    cfg.Listen,
+		Handler: mux,
+	}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	var err error
+	if cfg.CertFile != "" {
+		logrus.Infof("Serving credentials on %s", srv.Addr)
+		err = srv.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
+	} else {
+		logrus.Warnf("Serving credentials on %s over plain HTTP", srv.Addr)
+		err = srv.ListenAndServe()
+	}
+	if err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (s *server) serveToken(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, refreshToken, ok := req.BasicAuth()
+	if !ok {
+		rw.Header().Set("WWW-Authenticate", `Basic realm="klum"`)
+		http.Error(rw, errUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	token, err := s.issue(req.Context(), name, refreshToken)
+	if err == errUnauthorized {
+		http.Error(rw, err.Error(), http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		logrus.Errorf("Failed to issue token for user %s: %v", name, err)
+		http.Error(rw, "failed to issue token", http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(token); err != nil {
+		logrus.Errorf("Failed to write token: %v", err)
+	}
+}
+
+// issue returns a token for the ServiceAccount of the user if the refresh
+// token is the one issued for it
+func (s *server) issue(ctx context.Context, name, refreshToken string) (*Token, error) {
+	sa, err := s.serviceAccounts.Get(s.cfg.Namespace, name)
+	if errors.IsNotFound(err) {
+		// the user doesn't exist or isn't active
+		return nil, errUnauthorized
+	} else if err != nil {
+		return nil, err
+	}
+	if sa.Annotations["klum.cattle.io/user"] != name {
+		return nil, errUnauthorized
+	}
+
+	secret, err := s.secrets.Get(s.cfg.Namespace, user.RefreshSecretName(name))
+	if errors.IsNotFound(err) {
+		return nil, errUnauthorized
+	} else if err != nil {
+		return nil, err
+	}
+	expected := secret.Data[user.RefreshTokenKey]
+	if secret.Type != user.RefreshSecretType || !metav1.IsControlledBy(secret, sa) || len(expected) == 0 ||
+		subtle.ConstantTimeCompare(expected, []byte(refreshToken)) != 1 {
+		return nil, errUnauthorized
+	}
+
+	seconds := int64(s.cfg.TokenLifetime / time.Second)
+	token, err := s.coreClient.ServiceAccounts(s.cfg.Namespace).CreateToken(ctx, sa.Name, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences:         s.cfg.TokenAudiences,
+			ExpirationSeconds: &seconds,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Token{
+		Token:               token.Status.Token,
+		ExpirationTimestamp: token.Status.ExpirationTimestamp,
+	}, nil
+}
@@ -0,0 +1,106 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+	"golang.org/x/crypto/ssh"
+)
+
+// ageVersion starts the header of binary age files, see
+// https://age-encryption.org/v1
+const ageVersion = "age-encryption.org/v1"
+
+// ageRecipient is an age X25519 recipient or an ssh-ed25519 public key
+type ageRecipient struct {
+	recipient age.Recipient
+}
+
+func parseX25519Recipient(s string) (*ageRecipient, error) {
+	recipient, err := age.ParseX25519Recipient(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid age recipient: %v", err)
+	}
+	return &ageRecipient{recipient: recipient}, nil
+}
+
+func parseSSHRecipient(s string) (*ageRecipient, error) {
+	if keyType := strings.Fields(s)[0]; keyType != ssh.KeyAlgoED25519 {
+		return nil, fmt.Errorf("unsupported SSH key type %s, only ssh-ed25519 keys are supported", keyType)
+	}
+	recipient, err := agessh.ParseRecipient(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSH public key: %v", err)
+	}
+	return &ageRecipient{recipient: recipient}, nil
+}
+
+// Encrypt returns an armored age file of plaintext
+func (r *ageRecipient) Encrypt(plaintext []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	armored := armor.NewWriter(buf)
+	w, err := age.Encrypt(armored, r.recipient)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if err := armored.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decryptAge decrypts a binary or armored age file
+func decryptAge(ciphertext []byte, identities []age.Identity) ([]byte, error) {
+	var src io.Reader = bytes.NewReader(ciphertext)
+	if bytes.HasPrefix(bytes.TrimSpace(ciphertext), []byte(armor.Header)) {
+		src = armor.NewReader(src)
+	}
+	plaintext, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(plaintext)
+}
+
+// parseAgeIdentities parses an age identity file or an OpenSSH private key,
+// the passphrase decrypts encrypted SSH keys
+func parseAgeIdentities(data, passphrase []byte) ([]age.Identity, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN")) {
+		identities, err := age.ParseIdentities(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid age identity file: %v", err)
+		}
+		return identities, nil
+	}
+
+	identity, err := agessh.ParseIdentity(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if len(passphrase) == 0 {
+			return nil, errors.New("the SSH private key is encrypted, a passphrase is needed")
+		}
+		if missing.PublicKey == nil {
+			return nil, errors.New("the encrypted SSH private key has no public key")
+		}
+		identity, err = agessh.NewEncryptedSSHIdentity(missing.PublicKey, data, func() ([]byte, error) {
+			return passphrase, nil
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSH private key: %v", err)
+	}
+	return []age.Identity{identity}, nil
+}
@@ -0,0 +1,206 @@
+package encryption
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"filippo.io/age/armor"
+)
+
+// The files in testdata were generated by age-keygen and ssh-keygen,
+// id_ed25519_pass is encrypted with the passphrase s3cret. The .age files were
+// encrypted to them by the age command.
+const (
+	testRecipient = "age1mvskr2zkt0q90cqd0vk2grk2ersxhq2nu37wf3jctle0dxmhldts68r0p6"
+	testPlaintext = "apiVersion: v1\nkind: Config\ncurrent-context: default\n"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecryptAge(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		key        string
+		passphrase string
+		valid      bool
+	}{
+		{
+			name:  "age identity",
+			file:  "x25519.age",
+			key:   "age_identity.txt",
+			valid: true,
+		},
+		{
+			name:  "unencrypted ssh key",
+			file:  "ssh.age",
+			key:   "id_ed25519",
+			valid: true,
+		},
+		{
+			name:       "encrypted ssh key",
+			file:       "ssh_pass.age",
+			key:        "id_ed25519_pass",
+			passphrase: "s3cret",
+			valid:      true,
+		},
+		{
+			name: "encrypted ssh key without passphrase",
+			file: "ssh_pass.age",
+			key:  "id_ed25519_pass",
+		},
+		{
+			name:       "encrypted ssh key with wrong passphrase",
+			file:       "ssh_pass.age",
+			key:        "id_ed25519_pass",
+			passphrase: "secret",
+		},
+		{
+			name: "other ssh key",
+			file: "ssh.age",
+			key:  "id_ed25519_pass",
+			// the passphrase is right, the key isn't a recipient
+			passphrase: "s3cret",
+		},
+		{
+			name: "age identity for ssh recipient",
+			file: "ssh.age",
+			key:  "age_identity.txt",
+		},
+		{
+			name: "ssh key for age recipient",
+			file: "x25519.age",
+			key:  "id_ed25519",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext, err := Decrypt(readTestdata(t, tt.file), readTestdata(t, tt.key), []byte(tt.passphrase))
+			if !tt.valid {
+				if err == nil {
+					t.Error("Decrypt() succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(plaintext) != testPlaintext {
+				t.Errorf("plaintext = %q, want %q", plaintext, testPlaintext)
+			}
+		})
+	}
+}
+
+func TestAgeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		publicKey  string
+		privateKey string
+		passphrase string
+	}{
+		{
+			name:       "age recipient",
+			publicKey:  testRecipient,
+			privateKey: "age_identity.txt",
+		},
+		{
+			name:       "unencrypted ssh key",
+			publicKey:  string(readTestdata(t, "id_ed25519.pub")),
+			privateKey: "id_ed25519",
+		},
+		{
+			name:       "encrypted ssh key",
+			publicKey:  string(readTestdata(t, "id_ed25519_pass.pub")),
+			privateKey: "id_ed25519_pass",
+			passphrase: "s3cret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRecipient(tt.publicKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ciphertext, err := r.Encrypt([]byte(testPlaintext))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(ciphertext, []byte(armor.Header+"\n")) {
+				t.Fatalf("ciphertext isn't armored:\n%s", ciphertext)
+			}
+			plaintext, err := Decrypt(ciphertext, readTestdata(t, tt.privateKey), []byte(tt.passphrase))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(plaintext) != testPlaintext {
+				t.Errorf("plaintext = %q, want %q", plaintext, testPlaintext)
+			}
+		})
+	}
+}
+
+func TestParseRecipient(t *testing.T) {
+	tests := []struct {
+		name      string
+		publicKey string
+		valid     bool
+	}{
+		{
+			name:      "age recipient",
+			publicKey: testRecipient,
+			valid:     true,
+		},
+		{
+			name:      "age recipient with wrong checksum",
+			publicKey: "age1mvskr2zkt0q90cqd0vk2grk2ersxhq2nu37wf3jctle0dxmhldts68r0p7",
+		},
+		{
+			name:      "age identity instead of recipient",
+			publicKey: "AGE-SECRET-KEY-18P7MN3DGZT0XKG09GH8NQLD8Y6SV03EKX66YW2XDH898T7J6QG8QX8TUTG",
+		},
+		{
+			name:      "ssh-ed25519",
+			publicKey: string(readTestdata(t, "id_ed25519.pub")),
+			valid:     true,
+		},
+		{
+			name:      "ssh-ed25519 without comment",
+			publicKey: strings.Join(strings.Fields(string(readTestdata(t, "id_ed25519.pub")))[:2], " "),
+			valid:     true,
+		},
+		{
+			name:      "ssh-rsa",
+			publicKey: string(readTestdata(t, "id_rsa.pub")),
+		},
+		{
+			name:      "truncated ssh-ed25519",
+			publicKey: string(readTestdata(t, "id_ed25519.pub"))[:50],
+		},
+		{
+			name:      "unknown",
+			publicKey: "ecdsa-sha2-nistp256",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRecipient(tt.publicKey)
+			if tt.valid && err != nil {
+				t.Errorf("ParseRecipient() = %v", err)
+			} else if !tt.valid && err == nil {
+				t.Error("ParseRecipient() succeeded for an unsupported key")
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"filippo.io/age/armor"
+)
+
+// Recipient encrypts data to a public key
+type Recipient interface {
+	// Encrypt returns the ASCII armored ciphertext of plaintext
+	Encrypt(plaintext []byte) ([]byte, error)
+}
+
+// ParseRecipient parses an age X25519 recipient, an ssh-ed25519 public key or
+// an armored OpenPGP public key. Data encrypted to age and SSH keys is an age
+// file, data encrypted to OpenPGP keys is an OpenPGP message.
+func ParseRecipient(publicKey string) (Recipient, error) {
+	key := strings.TrimSpace(publicKey)
+	switch {
+	case strings.HasPrefix(key, "age1"):
+		return parseX25519Recipient(key)
+	case strings.HasPrefix(key, "ssh-"):
+		return parseSSHRecipient(key)
+	case strings.HasPrefix(key, pgpPublicKeyHeader):
+		return parsePGPRecipient(key)
+	}
+	return nil, errors.New("unsupported public key, expected an age X25519 recipient, an ssh-ed25519 key or an armored OpenPGP public key")
+}
+
+// Decrypt decrypts an age file or an armored OpenPGP message with the identity,
+// an age identity file or OpenSSH ed25519 private key for age files and an
+// armored OpenPGP private key for OpenPGP messages. The passphrase unlocks
+// encrypted SSH and OpenPGP keys.
+func Decrypt(ciphertext, identity, passphrase []byte) ([]byte, error) {
+	text := bytes.TrimSpace(ciphertext)
+	switch {
+	case bytes.HasPrefix(text, []byte(pgpMessageHeader)):
+		return decryptPGP(text, identity, passphrase)
+	case bytes.HasPrefix(text, []byte(armor.Header)) || bytes.HasPrefix(text, []byte(ageVersion)):
+		identities, err := parseAgeIdentities(identity, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return decryptAge(ciphertext, identities)
+	}
+	return nil, errors.New("not an age file or an armored OpenPGP message")
+}
@@ -0,0 +1,92 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+const (
+	pgpPublicKeyHeader = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+	pgpMessageHeader   = "-----BEGIN PGP MESSAGE-----"
+)
+
+// pgpRecipient is an armored OpenPGP public key
+type pgpRecipient struct {
+	entities openpgp.EntityList
+}
+
+func parsePGPRecipient(s string) (*pgpRecipient, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenPGP public key: %v", err)
+	}
+	if len(entities) == 0 {
+		return nil, errors.New("no OpenPGP public key found")
+	}
+	return &pgpRecipient{entities: entities}, nil
+}
+
+// Encrypt returns an armored OpenPGP message of plaintext
+func (r *pgpRecipient) Encrypt(plaintext []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	armored, err := armor.Encode(buf, "PGP MESSAGE", nil)
+	if err != nil {
+		return nil, err
+	}
+	w, err := openpgp.Encrypt(armored, r.entities, nil, &openpgp.FileHints{IsBinary: true}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if err := armored.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// decryptPGP decrypts an armored OpenPGP message with an armored private key
+func decryptPGP(ciphertext, identity, passphrase []byte) ([]byte, error) {
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(identity))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenPGP private key: %v", err)
+	}
+	for _, entity := range keyring {
+		decryptPGPKey(entity.PrivateKey, passphrase)
+		for _, subkey := range entity.Subkeys {
+			decryptPGPKey(subkey.PrivateKey, passphrase)
+		}
+	}
+
+	block, err := armor.Decode(bytes.NewReader(ciphertext))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenPGP message: %v", err)
+	}
+	md, err := openpgp.ReadMessage(block.Body, keyring, func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		return nil, errors.New("the OpenPGP private key is encrypted, the passphrase is missing or wrong")
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(md.UnverifiedBody)
+}
+
+// decryptPGPKey decrypts an encrypted private key, a wrong passphrase leaves it
+// encrypted and fails reading the message
+func decryptPGPKey(key *packet.PrivateKey, passphrase []byte) {
+	if key != nil && key.Encrypted && len(passphrase) > 0 {
+		_ = key.Decrypt(passphrase)
+	}
+}
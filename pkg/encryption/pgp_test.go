@@ -0,0 +1,100 @@
+package encryption
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+func armoredPGPKeys(t *testing.T, passphrase string) (string, []byte) {
+	entity, err := openpgp.NewEntity("darren", "", "darren@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public := &bytes.Buffer{}
+	w, err := armor.Encode(public, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	if passphrase != "" {
+		if err := entity.EncryptPrivateKeys([]byte(passphrase), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	private := &bytes.Buffer{}
+	w, err = armor.Encode(private, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SerializePrivateWithoutSigning(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	return public.String(), private.Bytes()
+}
+
+func TestPGPRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		keyPass    string
+		passphrase string
+		valid      bool
+	}{
+		{
+			name:  "unencrypted key",
+			valid: true,
+		},
+		{
+			name:       "encrypted key",
+			keyPass:    "s3cret",
+			passphrase: "s3cret",
+			valid:      true,
+		},
+		{
+			name:    "encrypted key without passphrase",
+			keyPass: "s3cret",
+		},
+		{
+			name:       "encrypted key with wrong passphrase",
+			keyPass:    "s3cret",
+			passphrase: "secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publicKey, privateKey := armoredPGPKeys(t, tt.keyPass)
+			r, err := ParseRecipient(publicKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ciphertext, err := r.Encrypt([]byte(testPlaintext))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			plaintext, err := Decrypt(ciphertext, privateKey, []byte(tt.passphrase))
+			if !tt.valid {
+				if err == nil {
+					t.Error("Decrypt() succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(plaintext) != testPlaintext {
+				t.Errorf("plaintext = %q, want %q", plaintext, testPlaintext)
+			}
+		})
+	}
+}
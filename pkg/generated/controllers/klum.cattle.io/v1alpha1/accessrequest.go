@@ -0,0 +1,376 @@
+/*
+Copyright 2022 Rancher Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by main. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	"time"
+
+	v1alpha1 "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	"github.com/rancher/lasso/pkg/client"
+	"github.com/rancher/lasso/pkg/controller"
+	"github.com/rancher/wrangler/pkg/apply"
+	"github.com/rancher/wrangler/pkg/condition"
+	"github.com/rancher/wrangler/pkg/generic"
+	"github.com/rancher/wrangler/pkg/kv"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+type AccessRequestHandler func(string, *v1alpha1.AccessRequest) (*v1alpha1.AccessRequest, error)
+
+type AccessRequestController interface {
+	generic.ControllerMeta
+	AccessRequestClient
+
+	OnChange(ctx context.Context, name string, sync AccessRequestHandler)
+	OnRemove(ctx context.Context, name string, sync AccessRequestHandler)
+	Enqueue(name string)
+	EnqueueAfter(name string, duration time.Duration)
+
+	Cache() AccessRequestCache
+}
+
+type AccessRequestClient interface {
+	Create(*v1alpha1.AccessRequest) (*v1alpha1.AccessRequest, error)
+	Update(*v1alpha1.AccessRequest) (*v1alpha1.AccessRequest, error)
+	UpdateStatus(*v1alpha1.AccessRequest) (*v1alpha1.AccessRequest, error)
+	Delete(name string, options *metav1.DeleteOptions) error
+	Get(name string, options metav1.GetOptions) (*v1alpha1.AccessRequest, error)
+	List(opts metav1.ListOptions) (*v1alpha1.AccessRequestList, error)
+	Watch(opts metav1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.AccessRequest, err error)
+}
+
+type AccessRequestCache interface {
+	Get(name string) (*v1alpha1.AccessRequest, error)
+	List(selector labels.Selector) ([]*v1alpha1.AccessRequest, error)
+
+	AddIndexer(indexName string, indexer AccessRequestIndexer)
+	GetByIndex(indexName, key string) ([]*v1alpha1.AccessRequest, error)
+}
+
+type AccessRequestIndexer func(obj *v1alpha1.AccessRequest) ([]string, error)
+
+type accessRequestController struct {
+	controller    controller.SharedController
+	client        *client.Client
+	gvk           schema.GroupVersionKind
+	groupResource schema.GroupResource
+}
+
+func NewAccessRequestController(gvk schema.GroupVersionKind, resource string, namespaced bool, controller controller.SharedControllerFactory) AccessRequestController {
+	c := controller.ForResourceKind(gvk.GroupVersion().WithResource(resource), gvk.Kind, namespaced)
+	return &accessRequestController{
+		controller: c,
+		client:     c.Client(),
+		gvk:        gvk,
+		groupResource: schema.GroupResource{
+			Group:    gvk.Group,
+			Resource: resource,
+		},
+	}
+}
+
+func FromAccessRequestHandlerToHandler(sync AccessRequestHandler) generic.Handler {
+	return func(key string, obj runtime.Object) (ret runtime.Object, err error) {
+		var v *v1alpha1.AccessRequest
+		if obj == nil {
+			v, err = sync(key, nil)
+		} else {
+			v, err = sync(key, obj.(*v1alpha1.AccessRequest))
+		}
+		if v == nil {
+			return nil, err
+		}
+		return v, err
+	}
+}
+
+func (c *accessRequestController) Updater() generic.Updater {
+	return func(obj runtime.Object) (runtime.Object, error) {
+		newObj, err := c.Update(obj.(*v1alpha1.AccessRequest))
+		if newObj == nil {
+			return nil, err
+		}
+		return newObj, err
+	}
+}
+
+func UpdateAccessRequestDeepCopyOnChange(client AccessRequestClient, obj *v1alpha1.AccessRequest, handler func(obj *v1alpha1.AccessRequest) (*v1alpha1.AccessRequest, error)) (*v1alpha1.AccessRequest, error) {
+	if obj == nil {
+		return obj, nil
+	}
+
+	copyObj := obj.DeepCopy()
+	newObj, err := handler(copyObj)
+	if newObj != nil {
+		copyObj = newObj
+	}
+	if obj.ResourceVersion == copyObj.ResourceVersion && !equality.Semantic.DeepEqual(obj, copyObj) {
+		return client.Update(copyObj)
+	}
+
+	return copyObj, err
+}
+
+func (c *accessRequestController) AddGenericHandler(ctx context.Context, name string, handler generic.Handler) {
+	c.controller.RegisterHandler(ctx, name, controller.SharedControllerHandlerFunc(handler))
+}
+
+func (c *accessRequestController) AddGenericRemoveHandler(ctx context.Context, name string, handler generic.Handler) {
+	c.AddGenericHandler(ctx, name, generic.NewRemoveHandler(name, c.Updater(), handler))
+}
+
+func (c *accessRequestController) OnChange(ctx context.Context, name string, sync AccessRequestHandler) {
+	c.AddGenericHandler(ctx, name, FromAccessRequestHandlerToHandler(sync))
+}
+
+func (c *accessRequestController) OnRemove(ctx context.Context, name string, sync AccessRequestHandler) {
+	c.AddGenericHandler(ctx, name, generic.NewRemoveHandler(name, c.Updater(), FromAccessRequestHandlerToHandler(sync)))
+}
+
+func (c *accessRequestController) Enqueue(name string) {
+	c.controller.Enqueue("", name)
+}
+
+func (c *accessRequestController) EnqueueAfter(name string, duration time.Duration) {
+	c.controller.EnqueueAfter("", name, duration)
+}
+
+func (c *accessRequestController) Informer() cache.SharedIndexInformer {
+	return c.controller.Informer()
+}
+
+func (c *accessRequestController) GroupVersionKind() schema.GroupVersionKind {
+	return c.gvk
+}
+
+func (c *accessRequestController) Cache() AccessRequestCache {
+	return &accessRequestCache{
+		indexer:  c.Informer().GetIndexer(),
+		resource: c.groupResource,
+	}
+}
+
+func (c *accessRequestController) Create(obj *v1alpha1.AccessRequest) (*v1alpha1.AccessRequest, error) {
+	result := &v1alpha1.AccessRequest{}
+	return result, c.client.Create(context.TODO(), "", obj, result, metav1.CreateOptions{})
+}
+
+func (c *accessRequestController) Update(obj *v1alpha1.AccessRequest) (*v1alpha1.AccessRequest, error) {
+	result := &v1alpha1.AccessRequest{}
+	return result, c.client.Update(context.TODO(), "", obj, result, metav1.UpdateOptions{})
+}
+
+func (c *accessRequestController) UpdateStatus(obj *v1alpha1.AccessRequest) (*v1alpha1.AccessRequest, error) {
+	result := &v1alpha1.AccessRequest{}
+	return result, c.client.UpdateStatus(context.TODO(), "", obj, result, metav1.UpdateOptions{})
+}
+
+func (c *accessRequestController) Delete(name string, options *metav1.DeleteOptions) error {
+	if options == nil {
+		options = &metav1.DeleteOptions{}
+	}
+	return c.client.Delete(context.TODO(), "", name, *options)
+}
+
+func (c *accessRequestController) Get(name string, options metav1.GetOptions) (*v1alpha1.AccessRequest, error) {
+	result := &v1alpha1.AccessRequest{}
+	return result, c.client.Get(context.TODO(), "", name, result, options)
+}
+
+func (c *accessRequestController) List(opts metav1.ListOptions) (*v1alpha1.AccessRequestList, error) {
+	result := &v1alpha1.AccessRequestList{}
+	return result, c.client.List(context.TODO(), "", result, opts)
+}
+
+func (c *accessRequestController) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	return c.client.Watch(context.TODO(), "", opts)
+}
+
+func (c *accessRequestController) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (*v1alpha1.AccessRequest, error) {
+	result := &v1alpha1.AccessRequest{}
+	return result, c.client.Patch(context.TODO(), "", name, pt, data, result, metav1.PatchOptions{}, subresources...)
+}
+
+type accessRequestCache struct {
+	indexer  cache.Indexer
+	resource schema.GroupResource
+}
+
+func (c *accessRequestCache) Get(name string) (*v1alpha1.AccessRequest, error) {
+	obj, exists, err := c.indexer.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(c.resource, name)
+	}
+	return obj.(*v1alpha1.AccessRequest), nil
+}
+
+func (c *accessRequestCache) List(selector labels.Selector) (ret []*v1alpha1.AccessRequest, err error) {
+
+	err = cache.ListAll(c.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.AccessRequest))
+	})
+
+	return ret, err
+}
+
+func (c *accessRequestCache) AddIndexer(indexName string, indexer AccessRequestIndexer) {
+	utilruntime.Must(c.indexer.AddIndexers(map[string]cache.IndexFunc{
+		indexName: func(obj interface{}) (strings []string, e error) {
+			return indexer(obj.(*v1alpha1.AccessRequest))
+		},
+	}))
+}
+
+func (c *accessRequestCache) GetByIndex(indexName, key string) (result []*v1alpha1.AccessRequest, err error) {
+	objs, err := c.indexer.ByIndex(indexName, key)
+	if err != nil {
+		return nil, err
+	}
+	result = make([]*v1alpha1.AccessRequest, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*v1alpha1.AccessRequest))
+	}
+	return result, nil
+}
+
+type AccessRequestStatusHandler func(obj *v1alpha1.AccessRequest, status v1alpha1.AccessRequestStatus) (v1alpha1.AccessRequestStatus, error)
+
+type AccessRequestGeneratingHandler func(obj *v1alpha1.AccessRequest, status v1alpha1.AccessRequestStatus) ([]runtime.Object, v1alpha1.AccessRequestStatus, error)
+
+func RegisterAccessRequestStatusHandler(ctx context.Context, controller AccessRequestController, condition condition.Cond, name string, handler AccessRequestStatusHandler) {
+	statusHandler := &accessRequestStatusHandler{
+		client:    controller,
+		condition: condition,
+		handler:   handler,
+	}
+	controller.AddGenericHandler(ctx, name, FromAccessRequestHandlerToHandler(statusHandler.sync))
+}
+
+func RegisterAccessRequestGeneratingHandler(ctx context.Context, controller AccessRequestController, apply apply.Apply,
+	condition condition.Cond, name string, handler AccessRequestGeneratingHandler, opts *generic.GeneratingHandlerOptions) {
+	statusHandler := &accessRequestGeneratingHandler{
+		AccessRequestGeneratingHandler: handler,
+		apply:                          apply,
+		name:                           name,
+		gvk:                            controller.GroupVersionKind(),
+	}
+	if opts != nil {
+		statusHandler.opts = *opts
+	}
+	controller.OnChange(ctx, name, statusHandler.Remove)
+	RegisterAccessRequestStatusHandler(ctx, controller, condition, name, statusHandler.Handle)
+}
+
+type accessRequestStatusHandler struct {
+	client    AccessRequestClient
+	condition condition.Cond
+	handler   AccessRequestStatusHandler
+}
+
+func (a *accessRequestStatusHandler) sync(key string, obj *v1alpha1.AccessRequest) (*v1alpha1.AccessRequest, error) {
+	if obj == nil {
+		return obj, nil
+	}
+
+	origStatus := obj.Status.DeepCopy()
+	obj = obj.DeepCopy()
+	newStatus, err := a.handler(obj, obj.Status)
+	if err != nil {
+		// Revert to old status on error
+		newStatus = *origStatus.DeepCopy()
+	}
+
+	if a.condition != "" {
+		if errors.IsConflict(err) {
+			a.condition.SetError(&newStatus, "", nil)
+		} else {
+			a.condition.SetError(&newStatus, "", err)
+		}
+	}
+	if !equality.Semantic.DeepEqual(origStatus, &newStatus) {
+		if a.condition != "" {
+			// Since status has changed, update the lastUpdatedTime
+			a.condition.LastUpdated(&newStatus, time.Now().UTC().Format(time.RFC3339))
+		}
+
+		var newErr error
+		obj.Status = newStatus
+		newObj, newErr := a.client.UpdateStatus(obj)
+		if err == nil {
+			err = newErr
+		}
+		if newErr == nil {
+			obj = newObj
+		}
+	}
+	return obj, err
+}
+
+type accessRequestGeneratingHandler struct {
+	AccessRequestGeneratingHandler
+	apply apply.Apply
+	opts  generic.GeneratingHandlerOptions
+	gvk   schema.GroupVersionKind
+	name  string
+}
+
+func (a *accessRequestGeneratingHandler) Remove(key string, obj *v1alpha1.AccessRequest) (*v1alpha1.AccessRequest, error) {
+	if obj != nil {
+		return obj, nil
+	}
+
+	obj = &v1alpha1.AccessRequest{}
+	obj.Namespace, obj.Name = kv.RSplit(key, "/")
+	obj.SetGroupVersionKind(a.gvk)
+
+	return nil, generic.ConfigureApplyForObject(a.apply, obj, &a.opts).
+		WithOwner(obj).
+		WithSetID(a.name).
+		ApplyObjects()
+}
+
+func (a *accessRequestGeneratingHandler) Handle(obj *v1alpha1.AccessRequest, status v1alpha1.AccessRequestStatus) (v1alpha1.AccessRequestStatus, error) {
+	if !obj.DeletionTimestamp.IsZero() {
+		return status, nil
+	}
+
+	objs, newStatus, err := a.AccessRequestGeneratingHandler(obj, status)
+	if err != nil {
+		return newStatus, err
+	}
+
+	return newStatus, generic.ConfigureApplyForObject(a.apply, obj, &a.opts).
+		WithOwner(obj).
+		WithSetID(a.name).
+		ApplyObjects(objs...)
+}
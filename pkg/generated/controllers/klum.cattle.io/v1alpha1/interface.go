@@ -30,8 +30,14 @@ func init() {
 }
 
 type Interface interface {
+	AccessRequest() AccessRequestController
+	Credential() CredentialController
+	Group() GroupController
+	KlumPolicy() KlumPolicyController
 	Kubeconfig() KubeconfigController
+	RoleTemplate() RoleTemplateController
 	User() UserController
+	UserPermissions() UserPermissionsController
 }
 
 func New(controllerFactory controller.SharedControllerFactory) Interface {
@@ -44,9 +50,27 @@ type version struct {
 	controllerFactory controller.SharedControllerFactory
 }
 
+func (c *version) AccessRequest() AccessRequestController {
+	return NewAccessRequestController(schema.GroupVersionKind{Group: "klum.cattle.io", Version: "v1alpha1", Kind: "AccessRequest"}, "accessrequests", false, c.controllerFactory)
+}
+func (c *version) Credential() CredentialController {
+	return NewCredentialController(schema.GroupVersionKind{Group: "klum.cattle.io", Version: "v1alpha1", Kind: "Credential"}, "credentials", false, c.controllerFactory)
+}
+func (c *version) Group() GroupController {
+	return NewGroupController(schema.GroupVersionKind{Group: "klum.cattle.io", Version: "v1alpha1", Kind: "Group"}, "groups", false, c.controllerFactory)
+}
+func (c *version) KlumPolicy() KlumPolicyController {
+	return NewKlumPolicyController(schema.GroupVersionKind{Group: "klum.cattle.io", Version: "v1alpha1", Kind: "KlumPolicy"}, "klumpolicies", false, c.controllerFactory)
+}
 func (c *version) Kubeconfig() KubeconfigController {
 	return NewKubeconfigController(schema.GroupVersionKind{Group: "klum.cattle.io", Version: "v1alpha1", Kind: "Kubeconfig"}, "kubeconfigs", false, c.controllerFactory)
 }
+func (c *version) RoleTemplate() RoleTemplateController {
+	return NewRoleTemplateController(schema.GroupVersionKind{Group: "klum.cattle.io", Version: "v1alpha1", Kind: "RoleTemplate"}, "roletemplates", false, c.controllerFactory)
+}
 func (c *version) User() UserController {
 	return NewUserController(schema.GroupVersionKind{Group: "klum.cattle.io", Version: "v1alpha1", Kind: "User"}, "users", false, c.controllerFactory)
 }
+func (c *version) UserPermissions() UserPermissionsController {
+	return NewUserPermissionsController(schema.GroupVersionKind{Group: "klum.cattle.io", Version: "v1alpha1", Kind: "UserPermissions"}, "userpermissionses", false, c.controllerFactory)
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2022 Rancher Labs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by main. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	"time"
+
+	v1alpha1 "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	"github.com/rancher/lasso/pkg/client"
+	"github.com/rancher/lasso/pkg/controller"
+	"github.com/rancher/wrangler/pkg/generic"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+type KlumPolicyHandler func(string, *v1alpha1.KlumPolicy) (*v1alpha1.KlumPolicy, error)
+
+type KlumPolicyController interface {
+	generic.ControllerMeta
+	KlumPolicyClient
+
+	OnChange(ctx context.Context, name string, sync KlumPolicyHandler)
+	OnRemove(ctx context.Context, name string, sync KlumPolicyHandler)
+	Enqueue(name string)
+	EnqueueAfter(name string, duration time.Duration)
+
+	Cache() KlumPolicyCache
+}
+
+type KlumPolicyClient interface {
+	Create(*v1alpha1.KlumPolicy) (*v1alpha1.KlumPolicy, error)
+	Update(*v1alpha1.KlumPolicy) (*v1alpha1.KlumPolicy, error)
+
+	Delete(name string, options *metav1.DeleteOptions) error
+	Get(name string, options metav1.GetOptions) (*v1alpha1.KlumPolicy, error)
+	List(opts metav1.ListOptions) (*v1alpha1.KlumPolicyList, error)
+	Watch(opts metav1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.KlumPolicy, err error)
+}
+
+type KlumPolicyCache interface {
+	Get(name string) (*v1alpha1.KlumPolicy, error)
+	List(selector labels.Selector) ([]*v1alpha1.KlumPolicy, error)
+
+	AddIndexer(indexName string, indexer KlumPolicyIndexer)
+	GetByIndex(indexName, key string) ([]*v1alpha1.KlumPolicy, error)
+}
+
+type KlumPolicyIndexer func(obj *v1alpha1.KlumPolicy) ([]string, error)
+
+type klumPolicyController struct {
+	controller    controller.SharedController
+	client        *client.Client
+	gvk           schema.GroupVersionKind
+	groupResource schema.GroupResource
+}
+
+func NewKlumPolicyController(gvk schema.GroupVersionKind, resource string, namespaced bool, controller controller.SharedControllerFactory) KlumPolicyController {
+	c := controller.ForResourceKind(gvk.GroupVersion().WithResource(resource), gvk.Kind, namespaced)
+	return &klumPolicyController{
+		controller: c,
+		client:     c.Client(),
+		gvk:        gvk,
+		groupResource: schema.GroupResource{
+			Group:    gvk.Group,
+			Resource: resource,
+		},
+	}
+}
+
+func FromKlumPolicyHandlerToHandler(sync KlumPolicyHandler) generic.Handler {
+	return func(key string, obj runtime.Object) (ret runtime.Object, err error) {
+		var v *v1alpha1.KlumPolicy
+		if obj == nil {
+			v, err = sync(key, nil)
+		} else {
+			v, err = sync(key, obj.(*v1alpha1.KlumPolicy))
+		}
+		if v == nil {
+			return nil, err
+		}
+		return v, err
+	}
+}
+
+func (c *klumPolicyController) Updater() generic.Updater {
+	return func(obj runtime.Object) (runtime.Object, error) {
+		newObj, err := c.Update(obj.(*v1alpha1.KlumPolicy))
+		if newObj == nil {
+			return nil, err
+		}
+		return newObj, err
+	}
+}
+
+func UpdateKlumPolicyDeepCopyOnChange(client KlumPolicyClient, obj *v1alpha1.KlumPolicy, handler func(obj *v1alpha1.KlumPolicy) (*v1alpha1.KlumPolicy, error)) (*v1alpha1.KlumPolicy, error) {
+	if obj == nil {
+		return obj, nil
+	}
+
+	copyObj := obj.DeepCopy()
+	newObj, err := handler(copyObj)
+	if newObj != nil {
+		copyObj = newObj
+	}
+	if obj.ResourceVersion == copyObj.ResourceVersion && !equality.Semantic.DeepEqual(obj, copyObj) {
+		return client.Update(copyObj)
+	}
+
+	return copyObj, err
+}
+
+func (c *klumPolicyController) AddGenericHandler(ctx context.Context, name string, handler generic.Handler) {
+	c.controller.RegisterHandler(ctx, name, controller.SharedControllerHandlerFunc(handler))
+}
+
+func (c *klumPolicyController) AddGenericRemoveHandler(ctx context.Context, name string, handler generic.Handler) {
+	c.AddGenericHandler(ctx, name, generic.NewRemoveHandler(name, c.Updater(), handler))
+}
+
+func (c *klumPolicyController) OnChange(ctx context.Context, name string, sync KlumPolicyHandler) {
+	c.AddGenericHandler(ctx, name, FromKlumPolicyHandlerToHandler(sync))
+}
+
+func (c *klumPolicyController) OnRemove(ctx context.Context, name string, sync KlumPolicyHandler) {
+	c.AddGenericHandler(ctx, name, generic.NewRemoveHandler(name, c.Updater(), FromKlumPolicyHandlerToHandler(sync)))
+}
+
+func (c *klumPolicyController) Enqueue(name string) {
+	c.controller.Enqueue("", name)
+}
+
+func (c *klumPolicyController) EnqueueAfter(name string, duration time.Duration) {
+	c.controller.EnqueueAfter("", name, duration)
+}
+
+func (c *klumPolicyController) Informer() cache.SharedIndexInformer {
+	return c.controller.Informer()
+}
+
+func (c *klumPolicyController) GroupVersionKind() schema.GroupVersionKind {
+	return c.gvk
+}
+
+func (c *klumPolicyController) Cache() KlumPolicyCache {
+	return &klumPolicyCache{
+		indexer:  c.Informer().GetIndexer(),
+		resource: c.groupResource,
+	}
+}
+
+func (c *klumPolicyController) Create(obj *v1alpha1.KlumPolicy) (*v1alpha1.KlumPolicy, error) {
+	result := &v1alpha1.KlumPolicy{}
+	return result, c.client.Create(context.TODO(), "", obj, result, metav1.CreateOptions{})
+}
+
+func (c *klumPolicyController) Update(obj *v1alpha1.KlumPolicy) (*v1alpha1.KlumPolicy, error) {
+	result := &v1alpha1.KlumPolicy{}
+	return result, c.client.Update(context.TODO(), "", obj, result, metav1.UpdateOptions{})
+}
+
+func (c *klumPolicyController) Delete(name string, options *metav1.DeleteOptions) error {
+	if options == nil {
+		options = &metav1.DeleteOptions{}
+	}
+	return c.client.Delete(context.TODO(), "", name, *options)
+}
+
+func (c *klumPolicyController) Get(name string, options metav1.GetOptions) (*v1alpha1.KlumPolicy, error) {
+	result := &v1alpha1.KlumPolicy{}
+	return result, c.client.Get(context.TODO(), "", name, result, options)
+}
+
+func (c *klumPolicyController) List(opts metav1.ListOptions) (*v1alpha1.KlumPolicyList, error) {
+	result := &v1alpha1.KlumPolicyList{}
+	return result, c.client.List(context.TODO(), "", result, opts)
+}
+
+func (c *klumPolicyController) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	return c.client.Watch(context.TODO(), "", opts)
+}
+
+func (c *klumPolicyController) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (*v1alpha1.KlumPolicy, error) {
+	result := &v1alpha1.KlumPolicy{}
+	return result, c.client.Patch(context.TODO(), "", name, pt, data, result, metav1.PatchOptions{}, subresources...)
+}
+
+type klumPolicyCache struct {
+	indexer  cache.Indexer
+	resource schema.GroupResource
+}
+
+func (c *klumPolicyCache) Get(name string) (*v1alpha1.KlumPolicy, error) {
+	obj, exists, err := c.indexer.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(c.resource, name)
+	}
+	return obj.(*v1alpha1.KlumPolicy), nil
+}
+
+func (c *klumPolicyCache) List(selector labels.Selector) (ret []*v1alpha1.KlumPolicy, err error) {
+
+	err = cache.ListAll(c.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.KlumPolicy))
+	})
+
+	return ret, err
+}
+
+func (c *klumPolicyCache) AddIndexer(indexName string, indexer KlumPolicyIndexer) {
+	utilruntime.Must(c.indexer.AddIndexers(map[string]cache.IndexFunc{
+		indexName: func(obj interface{}) (strings []string, e error) {
+			return indexer(obj.(*v1alpha1.KlumPolicy))
+		},
+	}))
+}
+
+func (c *klumPolicyCache) GetByIndex(indexName, key string) (result []*v1alpha1.KlumPolicy, err error) {
+	objs, err := c.indexer.ByIndex(indexName, key)
+	if err != nil {
+		return nil, err
+	}
+	result = make([]*v1alpha1.KlumPolicy, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.(*v1alpha1.KlumPolicy))
+	}
+	return result, nil
+}
@@ -25,7 +25,10 @@ import (
 	v1alpha1 "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
 	"github.com/rancher/lasso/pkg/client"
 	"github.com/rancher/lasso/pkg/controller"
+	"github.com/rancher/wrangler/pkg/apply"
+	"github.com/rancher/wrangler/pkg/condition"
 	"github.com/rancher/wrangler/pkg/generic"
+	"github.com/rancher/wrangler/pkg/kv"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -55,7 +58,7 @@ type KubeconfigController interface {
 type KubeconfigClient interface {
 	Create(*v1alpha1.Kubeconfig) (*v1alpha1.Kubeconfig, error)
 	Update(*v1alpha1.Kubeconfig) (*v1alpha1.Kubeconfig, error)
-
+	UpdateStatus(*v1alpha1.Kubeconfig) (*v1alpha1.Kubeconfig, error)
 	Delete(name string, options *metav1.DeleteOptions) error
 	Get(name string, options metav1.GetOptions) (*v1alpha1.Kubeconfig, error)
 	List(opts metav1.ListOptions) (*v1alpha1.KubeconfigList, error)
@@ -184,6 +187,11 @@ func (c *kubeconfigController) Update(obj *v1alpha1.Kubeconfig) (*v1alpha1.Kubec
 	return result, c.client.Update(context.TODO(), "", obj, result, metav1.UpdateOptions{})
 }
 
+func (c *kubeconfigController) UpdateStatus(obj *v1alpha1.Kubeconfig) (*v1alpha1.Kubeconfig, error) {
+	result := &v1alpha1.Kubeconfig{}
+	return result, c.client.UpdateStatus(context.TODO(), "", obj, result, metav1.UpdateOptions{})
+}
+
 func (c *kubeconfigController) Delete(name string, options *metav1.DeleteOptions) error {
 	if options == nil {
 		options = &metav1.DeleteOptions{}
@@ -254,3 +262,115 @@ func (c *kubeconfigCache) GetByIndex(indexName, key string) (result []*v1alpha1.
 	}
 	return result, nil
 }
+
+type KubeconfigStatusHandler func(obj *v1alpha1.Kubeconfig, status v1alpha1.KubeconfigStatus) (v1alpha1.KubeconfigStatus, error)
+
+type KubeconfigGeneratingHandler func(obj *v1alpha1.Kubeconfig, status v1alpha1.KubeconfigStatus) ([]runtime.Object, v1alpha1.KubeconfigStatus, error)
+
+func RegisterKubeconfigStatusHandler(ctx context.Context, controller KubeconfigController, condition condition.Cond, name string, handler KubeconfigStatusHandler) {
+	statusHandler := &kubeconfigStatusHandler{
+		client:    controller,
+		condition: condition,
+		handler:   handler,
+	}
+	controller.AddGenericHandler(ctx, name, FromKubeconfigHandlerToHandler(statusHandler.sync))
+}
+
+func RegisterKubeconfigGeneratingHandler(ctx context.Context, controller KubeconfigController, apply apply.Apply,
+	condition condition.Cond, name string, handler KubeconfigGeneratingHandler, opts *generic.GeneratingHandlerOptions) {
+	statusHandler := &kubeconfigGeneratingHandler{
+		KubeconfigGeneratingHandler: handler,
+		apply:                       apply,
+		name:                        name,
+		gvk:                         controller.GroupVersionKind(),
+	}
+	if opts != nil {
+		statusHandler.opts = *opts
+	}
+	controller.OnChange(ctx, name, statusHandler.Remove)
+	RegisterKubeconfigStatusHandler(ctx, controller, condition, name, statusHandler.Handle)
+}
+
+type kubeconfigStatusHandler struct {
+	client    KubeconfigClient
+	condition condition.Cond
+	handler   KubeconfigStatusHandler
+}
+
+func (a *kubeconfigStatusHandler) sync(key string, obj *v1alpha1.Kubeconfig) (*v1alpha1.Kubeconfig, error) {
+	if obj == nil {
+		return obj, nil
+	}
+
+	origStatus := obj.Status.DeepCopy()
+	obj = obj.DeepCopy()
+	newStatus, err := a.handler(obj, obj.Status)
+	if err != nil {
+		// Revert to old status on error
+		newStatus = *origStatus.DeepCopy()
+	}
+
+	if a.condition != "" {
+		if errors.IsConflict(err) {
+			a.condition.SetError(&newStatus, "", nil)
+		} else {
+			a.condition.SetError(&newStatus, "", err)
+		}
+	}
+	if !equality.Semantic.DeepEqual(origStatus, &newStatus) {
+		if a.condition != "" {
+			// Since status has changed, update the lastUpdatedTime
+			a.condition.LastUpdated(&newStatus, time.Now().UTC().Format(time.RFC3339))
+		}
+
+		var newErr error
+		obj.Status = newStatus
+		newObj, newErr := a.client.UpdateStatus(obj)
+		if err == nil {
+			err = newErr
+		}
+		if newErr == nil {
+			obj = newObj
+		}
+	}
+	return obj, err
+}
+
+type kubeconfigGeneratingHandler struct {
+	KubeconfigGeneratingHandler
+	apply apply.Apply
+	opts  generic.GeneratingHandlerOptions
+	gvk   schema.GroupVersionKind
+	name  string
+}
+
+func (a *kubeconfigGeneratingHandler) Remove(key string, obj *v1alpha1.Kubeconfig) (*v1alpha1.Kubeconfig, error) {
+	if obj != nil {
+		return obj, nil
+	}
+
+	obj = &v1alpha1.Kubeconfig{}
+	obj.Namespace, obj.Name = kv.RSplit(key, "/")
+	obj.SetGroupVersionKind(a.gvk)
+
+	return nil, generic.ConfigureApplyForObject(a.apply, obj, &a.opts).
+		WithOwner(obj).
+		WithSetID(a.name).
+		ApplyObjects()
+}
+
+func (a *kubeconfigGeneratingHandler) Handle(obj *v1alpha1.Kubeconfig, status v1alpha1.KubeconfigStatus) (v1alpha1.KubeconfigStatus, error) {
+	if !obj.DeletionTimestamp.IsZero() {
+		return status, nil
+	}
+
+	objs, newStatus, err := a.KubeconfigGeneratingHandler(obj, status)
+	if err != nil {
+		return newStatus, err
+	}
+
+	return newStatus, generic.ConfigureApplyForObject(a.apply, obj, &a.opts).
+		WithOwner(obj).
+		WithSetID(a.name).
+		ApplyObjects(objs...)
+}
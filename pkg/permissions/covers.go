@@ -0,0 +1,115 @@
+package permissions
+
+import (
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// Covers returns whether the owner rules grant everything the requested rules
+// grant and the requested rules that aren't fully covered.
+func Covers(owner, requested []rbacv1.PolicyRule) (bool, []rbacv1.PolicyRule) {
+	var uncovered []rbacv1.PolicyRule
+	for _, rule := range requested {
+		for _, atomic := range breakdown(rule) {
+			if !anyCovers(owner, atomic) {
+				uncovered = append(uncovered, rule)
+				break
+			}
+		}
+	}
+	return len(uncovered) == 0, uncovered
+}
+
+// breakdown splits a rule into rules with a single verb, API group, resource,
+// resource name or non-resource URL each
+func breakdown(rule rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	var result []rbacv1.PolicyRule
+	for _, verb := range rule.Verbs {
+		for _, url := range rule.NonResourceURLs {
+			result = append(result, rbacv1.PolicyRule{
+				Verbs:           []string{verb},
+				NonResourceURLs: []string{url},
+			})
+		}
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				if len(rule.ResourceNames) == 0 {
+					result = append(result, rbacv1.PolicyRule{
+						Verbs:     []string{verb},
+						APIGroups: []string{group},
+						Resources: []string{resource},
+					})
+					continue
+				}
+				for _, resourceName := range rule.ResourceNames {
+					result = append(result, rbacv1.PolicyRule{
+						Verbs:         []string{verb},
+						APIGroups:     []string{group},
+						Resources:     []string{resource},
+						ResourceNames: []string{resourceName},
+					})
+				}
+			}
+		}
+	}
+	return result
+}
+
+func anyCovers(owner []rbacv1.PolicyRule, atomic rbacv1.PolicyRule) bool {
+	for _, rule := range owner {
+		if covers(rule, atomic) {
+			return true
+		}
+	}
+	return false
+}
+
+// covers returns whether the owner rule grants the atomic rule
+func covers(owner, atomic rbacv1.PolicyRule) bool {
+	if !hasAll(owner.Verbs, atomic.Verbs[0]) {
+		return false
+	}
+
+	if len(atomic.NonResourceURLs) > 0 {
+		for _, url := range owner.NonResourceURLs {
+			if url == atomic.NonResourceURLs[0] ||
+				strings.HasSuffix(url, "*") && strings.HasPrefix(atomic.NonResourceURLs[0], strings.TrimSuffix(url, "*")) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !hasAll(owner.APIGroups, atomic.APIGroups[0]) ||
+		!coversResource(owner.Resources, atomic.Resources[0]) {
+		return false
+	}
+
+	if len(owner.ResourceNames) == 0 {
+		return true
+	}
+	return len(atomic.ResourceNames) > 0 && hasAll(owner.ResourceNames, atomic.ResourceNames[0])
+}
+
+func hasAll(values []string, value string) bool {
+	for _, v := range values {
+		if v == rbacv1.VerbAll || v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// coversResource also matches "*/subresource" against any resource with that
+// subresource
+func coversResource(resources []string, resource string) bool {
+	if hasAll(resources, resource) {
+		return true
+	}
+	i := strings.Index(resource, "/")
+	if i < 0 {
+		return false
+	}
+	return hasAll(resources, "*"+resource[i:])
+}
@@ -0,0 +1,214 @@
+package permissions
+
+import (
+	"reflect"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		name      string
+		owner     []rbacv1.PolicyRule
+		requested []rbacv1.PolicyRule
+		covered   bool
+	}{
+		{
+			name:      "same rule",
+			owner:     []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"pods"})},
+			requested: []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"pods"})},
+			covered:   true,
+		},
+		{
+			name:      "nothing requested",
+			requested: nil,
+			covered:   true,
+		},
+		{
+			name:      "nothing owned",
+			requested: []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"pods"})},
+		},
+		{
+			name:      "missing verb",
+			owner:     []rbacv1.PolicyRule{rule([]string{"get", "list"}, []string{""}, []string{"pods"})},
+			requested: []rbacv1.PolicyRule{rule([]string{"get", "delete"}, []string{""}, []string{"pods"})},
+		},
+		{
+			name: "covered by several rules",
+			owner: []rbacv1.PolicyRule{
+				rule([]string{"get"}, []string{""}, []string{"pods", "secrets"}),
+				rule([]string{"delete"}, []string{""}, []string{"pods"}),
+			},
+			requested: []rbacv1.PolicyRule{rule([]string{"get", "delete"}, []string{""}, []string{"pods"})},
+			covered:   true,
+		},
+		{
+			name:      "wildcard verb",
+			owner:     []rbacv1.PolicyRule{rule([]string{"*"}, []string{""}, []string{"pods"})},
+			requested: []rbacv1.PolicyRule{rule([]string{"get", "deletecollection"}, []string{""}, []string{"pods"})},
+			covered:   true,
+		},
+		{
+			name:      "wildcard verb requested",
+			owner:     []rbacv1.PolicyRule{rule([]string{"get", "list", "watch", "create", "update", "patch", "delete"}, []string{""}, []string{"pods"})},
+			requested: []rbacv1.PolicyRule{rule([]string{"*"}, []string{""}, []string{"pods"})},
+		},
+		{
+			name:      "wildcard api group",
+			owner:     []rbacv1.PolicyRule{rule([]string{"get"}, []string{"*"}, []string{"deployments"})},
+			requested: []rbacv1.PolicyRule{rule([]string{"get"}, []string{"apps", "extensions"}, []string{"deployments"})},
+			covered:   true,
+		},
+		{
+			name:      "wildcard api group requested",
+			owner:     []rbacv1.PolicyRule{rule([]string{"get"}, []string{"apps"}, []string{"deployments"})},
+			requested: []rbacv1.PolicyRule{rule([]string{"get"}, []string{"*"}, []string{"deployments"})},
+		},
+		{
+			name:      "other api group",
+			owner:     []rbacv1.PolicyRule{rule([]string{"get"}, []string{"apps"}, []string{"deployments"})},
+			requested: []rbacv1.PolicyRule{rule([]string{"get"}, []string{"extensions"}, []string{"deployments"})},
+		},
+		{
+			name:      "wildcard resource",
+			owner:     []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"*"})},
+			requested: []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"pods", "secrets"})},
+			covered:   true,
+		},
+		{
+			name:      "wildcard resource covers subresources",
+			owner:     []rbacv1.PolicyRule{rule([]string{"create"}, []string{""}, []string{"*"})},
+			requested: []rbacv1.PolicyRule{rule([]string{"create"}, []string{""}, []string{"pods/exec"})},
+			covered:   true,
+		},
+		{
+			name:      "wildcard resource requested",
+			owner:     []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"pods", "secrets"})},
+			requested: []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"*"})},
+		},
+		{
+			name:      "resource doesn't cover its subresources",
+			owner:     []rbacv1.PolicyRule{rule([]string{"create"}, []string{""}, []string{"pods"})},
+			requested: []rbacv1.PolicyRule{rule([]string{"create"}, []string{""}, []string{"pods/exec"})},
+		},
+		{
+			name:      "subresource doesn't cover its resource",
+			owner:     []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"pods/log"})},
+			requested: []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"pods"})},
+		},
+		{
+			name:      "other subresource",
+			owner:     []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"pods/log"})},
+			requested: []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"pods/exec"})},
+		},
+		{
+			name:      "subresource of any resource",
+			owner:     []rbacv1.PolicyRule{rule([]string{"update"}, []string{"apps"}, []string{"*/scale"})},
+			requested: []rbacv1.PolicyRule{rule([]string{"update"}, []string{"apps"}, []string{"deployments/scale", "statefulsets/scale"})},
+			covered:   true,
+		},
+		{
+			name:      "subresource of any resource doesn't cover the resource",
+			owner:     []rbacv1.PolicyRule{rule([]string{"update"}, []string{"apps"}, []string{"*/scale"})},
+			requested: []rbacv1.PolicyRule{rule([]string{"update"}, []string{"apps"}, []string{"deployments"})},
+		},
+		{
+			name:  "resource names within owned names",
+			owner: []rbacv1.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"a", "b"}}},
+			requested: []rbacv1.PolicyRule{
+				{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"b"}},
+			},
+			covered: true,
+		},
+		{
+			name:  "resource name not owned",
+			owner: []rbacv1.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"a"}}},
+			requested: []rbacv1.PolicyRule{
+				{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"a", "c"}},
+			},
+		},
+		{
+			name:      "all names requested but only some owned",
+			owner:     []rbacv1.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"a"}}},
+			requested: []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"configmaps"})},
+		},
+		{
+			name:  "all names owned",
+			owner: []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"configmaps"})},
+			requested: []rbacv1.PolicyRule{
+				{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"a"}},
+			},
+			covered: true,
+		},
+		{
+			name:      "non-resource URL",
+			owner:     []rbacv1.PolicyRule{{Verbs: []string{"get"}, NonResourceURLs: []string{"/healthz", "/version"}}},
+			requested: []rbacv1.PolicyRule{{Verbs: []string{"get"}, NonResourceURLs: []string{"/healthz"}}},
+			covered:   true,
+		},
+		{
+			name:      "other non-resource URL",
+			owner:     []rbacv1.PolicyRule{{Verbs: []string{"get"}, NonResourceURLs: []string{"/healthz"}}},
+			requested: []rbacv1.PolicyRule{{Verbs: []string{"get"}, NonResourceURLs: []string{"/metrics"}}},
+		},
+		{
+			name:      "non-resource URL prefix",
+			owner:     []rbacv1.PolicyRule{{Verbs: []string{"get"}, NonResourceURLs: []string{"/healthz/*"}}},
+			requested: []rbacv1.PolicyRule{{Verbs: []string{"get"}, NonResourceURLs: []string{"/healthz/etcd", "/healthz/ping"}}},
+			covered:   true,
+		},
+		{
+			name:      "non-resource URL prefix doesn't cover other paths",
+			owner:     []rbacv1.PolicyRule{{Verbs: []string{"get"}, NonResourceURLs: []string{"/healthz/*"}}},
+			requested: []rbacv1.PolicyRule{{Verbs: []string{"get"}, NonResourceURLs: []string{"/metrics"}}},
+		},
+		{
+			name:      "non-resource URL wildcard",
+			owner:     []rbacv1.PolicyRule{{Verbs: []string{"get"}, NonResourceURLs: []string{"*"}}},
+			requested: []rbacv1.PolicyRule{{Verbs: []string{"get"}, NonResourceURLs: []string{"/metrics", "/healthz/etcd"}}},
+			covered:   true,
+		},
+		{
+			name:      "non-resource URL wildcard requested",
+			owner:     []rbacv1.PolicyRule{{Verbs: []string{"get"}, NonResourceURLs: []string{"/healthz/*"}}},
+			requested: []rbacv1.PolicyRule{{Verbs: []string{"get"}, NonResourceURLs: []string{"*"}}},
+		},
+		{
+			name:      "resources don't cover non-resource URLs",
+			owner:     []rbacv1.PolicyRule{rule([]string{"*"}, []string{"*"}, []string{"*"})},
+			requested: []rbacv1.PolicyRule{{Verbs: []string{"get"}, NonResourceURLs: []string{"/metrics"}}},
+		},
+		{
+			name:      "cluster-admin covers everything",
+			owner:     []rbacv1.PolicyRule{rule([]string{"*"}, []string{"*"}, []string{"*"}), {Verbs: []string{"*"}, NonResourceURLs: []string{"*"}}},
+			requested: []rbacv1.PolicyRule{rule([]string{"*"}, []string{"*"}, []string{"*"}), {Verbs: []string{"*"}, NonResourceURLs: []string{"*"}}},
+			covered:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			covered, uncovered := Covers(tt.owner, tt.requested)
+			if covered != tt.covered {
+				t.Errorf("Covers() = %v, want %v", covered, tt.covered)
+			}
+			if covered != (len(uncovered) == 0) {
+				t.Errorf("uncovered rules %v don't match Covers() = %v", uncovered, covered)
+			}
+		})
+	}
+}
+
+func TestCoversReportsUncoveredRules(t *testing.T) {
+	owner := []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"pods"})}
+	requested := []rbacv1.PolicyRule{
+		rule([]string{"get"}, []string{""}, []string{"pods"}),
+		rule([]string{"get"}, []string{""}, []string{"secrets"}),
+	}
+
+	_, uncovered := Covers(owner, requested)
+	if !reflect.DeepEqual(uncovered, requested[1:]) {
+		t.Errorf("uncovered = %v, want %v", uncovered, requested[1:])
+	}
+}
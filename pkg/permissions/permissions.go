@@ -0,0 +1,146 @@
+package permissions
+
+import (
+	"sort"
+	"strings"
+
+	rbaccontroller "github.com/rancher/wrangler-api/pkg/generated/controllers/rbac/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Resolver looks up the rules granted by Roles and ClusterRoles
+type Resolver struct {
+	clusterRoles rbaccontroller.ClusterRoleCache
+	roles        rbaccontroller.RoleCache
+}
+
+func NewResolver(clusterRoles rbaccontroller.ClusterRoleCache, roles rbaccontroller.RoleCache) *Resolver {
+	return &Resolver{
+		clusterRoles: clusterRoles,
+		roles:        roles,
+	}
+}
+
+// Rules returns the rules of the role referenced by a binding in namespace. Roles
+// that don't exist grant nothing.
+func (r *Resolver) Rules(namespace string, ref rbacv1.RoleRef) ([]rbacv1.PolicyRule, error) {
+	switch ref.Kind {
+	case "ClusterRole":
+		return r.ClusterRoleRules(ref.Name)
+	case "Role":
+		role, err := r.roles.Get(namespace, ref.Name)
+		if errors.IsNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return role.Rules, nil
+	}
+	return nil, nil
+}
+
+// ClusterRoleRules returns the rules of a ClusterRole including the rules of all
+// ClusterRoles aggregated into it
+func (r *Resolver) ClusterRoleRules(name string) ([]rbacv1.PolicyRule, error) {
+	return r.clusterRoleRules(name, map[string]bool{})
+}
+
+func (r *Resolver) clusterRoleRules(name string, seen map[string]bool) ([]rbacv1.PolicyRule, error) {
+	if seen[name] {
+		return nil, nil
+	}
+	seen[name] = true
+
+	clusterRole, err := r.clusterRoles.Get(name)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// the rules of an aggregated ClusterRole are filled in by the
+	// controller-manager, but may not be yet
+	rules := append([]rbacv1.PolicyRule{}, clusterRole.Rules...)
+	if clusterRole.AggregationRule == nil {
+		return rules, nil
+	}
+
+	for _, labelSelector := range clusterRole.AggregationRule.ClusterRoleSelectors {
+		selector, err := metav1.LabelSelectorAsSelector(&labelSelector)
+		if err != nil {
+			return nil, err
+		}
+		aggregated, err := r.clusterRoles.List(selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range aggregated {
+			otherRules, err := r.clusterRoleRules(other.Name, seen)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, otherRules...)
+		}
+	}
+
+	return rules, nil
+}
+
+// Flatten de-duplicates rules. Rules for the same resources, resource names and
+// non-resource URLs are merged into one rule with the union of their verbs.
+func Flatten(rules []rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	var (
+		keys   []string
+		merged = map[string]*rbacv1.PolicyRule{}
+	)
+
+	for _, rule := range rules {
+		rule := rbacv1.PolicyRule{
+			Verbs:           normalize(rule.Verbs),
+			APIGroups:       normalize(rule.APIGroups),
+			Resources:       normalize(rule.Resources),
+			ResourceNames:   normalize(rule.ResourceNames),
+			NonResourceURLs: normalize(rule.NonResourceURLs),
+		}
+		key := strings.Join([]string{
+			strings.Join(rule.APIGroups, ","),
+			strings.Join(rule.Resources, ","),
+			strings.Join(rule.ResourceNames, ","),
+			strings.Join(rule.NonResourceURLs, ","),
+		}, "|")
+
+		if existing, ok := merged[key]; ok {
+			existing.Verbs = normalize(append(existing.Verbs, rule.Verbs...))
+			continue
+		}
+		keys = append(keys, key)
+		merged[key] = &rule
+	}
+
+	sort.Strings(keys)
+	result := make([]rbacv1.PolicyRule, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *merged[key])
+	}
+	return result
+}
+
+// normalize sorts and de-duplicates values, a "*" replaces all other values
+func normalize(values []string) []string {
+	set := map[string]bool{}
+	for _, value := range values {
+		if value == rbacv1.VerbAll {
+			return []string{rbacv1.VerbAll}
+		}
+		set[value] = true
+	}
+
+	var result []string
+	for value := range set {
+		result = append(result, value)
+	}
+	sort.Strings(result)
+	return result
+}
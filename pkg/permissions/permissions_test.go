@@ -0,0 +1,232 @@
+package permissions
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	rbaccontroller "github.com/rancher/wrangler-api/pkg/generated/controllers/rbac/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func rule(verbs, apiGroups, resources []string) rbacv1.PolicyRule {
+	return rbacv1.PolicyRule{
+		Verbs:     verbs,
+		APIGroups: apiGroups,
+		Resources: resources,
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []rbacv1.PolicyRule
+		want  []rbacv1.PolicyRule
+	}{
+		{
+			name:  "nothing",
+			rules: nil,
+			want:  []rbacv1.PolicyRule{},
+		},
+		{
+			name: "verbs of the same resources are merged",
+			rules: []rbacv1.PolicyRule{
+				rule([]string{"list", "get"}, []string{""}, []string{"pods"}),
+				rule([]string{"watch", "get"}, []string{""}, []string{"pods"}),
+			},
+			want: []rbacv1.PolicyRule{
+				rule([]string{"get", "list", "watch"}, []string{""}, []string{"pods"}),
+			},
+		},
+		{
+			name: "wildcard verb replaces the others",
+			rules: []rbacv1.PolicyRule{
+				rule([]string{"get"}, []string{""}, []string{"pods"}),
+				rule([]string{"delete", "*"}, []string{""}, []string{"pods"}),
+			},
+			want: []rbacv1.PolicyRule{
+				rule([]string{"*"}, []string{""}, []string{"pods"}),
+			},
+		},
+		{
+			name: "wildcard resource replaces the others",
+			rules: []rbacv1.PolicyRule{
+				rule([]string{"get"}, []string{""}, []string{"pods", "*"}),
+				rule([]string{"list"}, []string{""}, []string{"*"}),
+			},
+			want: []rbacv1.PolicyRule{
+				rule([]string{"get", "list"}, []string{""}, []string{"*"}),
+			},
+		},
+		{
+			name: "order of resources doesn't matter",
+			rules: []rbacv1.PolicyRule{
+				rule([]string{"get"}, []string{"", "apps"}, []string{"secrets", "configmaps"}),
+				rule([]string{"list"}, []string{"apps", ""}, []string{"configmaps", "secrets", "secrets"}),
+			},
+			want: []rbacv1.PolicyRule{
+				rule([]string{"get", "list"}, []string{"", "apps"}, []string{"configmaps", "secrets"}),
+			},
+		},
+		{
+			name: "subresources are other resources",
+			rules: []rbacv1.PolicyRule{
+				rule([]string{"get"}, []string{""}, []string{"pods"}),
+				rule([]string{"create"}, []string{""}, []string{"pods/exec"}),
+			},
+			want: []rbacv1.PolicyRule{
+				rule([]string{"create"}, []string{""}, []string{"pods/exec"}),
+				rule([]string{"get"}, []string{""}, []string{"pods"}),
+			},
+		},
+		{
+			name: "resource names are kept apart",
+			rules: []rbacv1.PolicyRule{
+				{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"a"}},
+				rule([]string{"list"}, []string{""}, []string{"configmaps"}),
+				{Verbs: []string{"update"}, APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"a"}},
+			},
+			want: []rbacv1.PolicyRule{
+				{Verbs: []string{"get", "update"}, APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"a"}},
+				rule([]string{"list"}, []string{""}, []string{"configmaps"}),
+			},
+		},
+		{
+			name: "non-resource URLs",
+			rules: []rbacv1.PolicyRule{
+				{Verbs: []string{"get"}, NonResourceURLs: []string{"/healthz/*"}},
+				{Verbs: []string{"get"}, NonResourceURLs: []string{"/metrics"}},
+				{Verbs: []string{"post"}, NonResourceURLs: []string{"/healthz/*"}},
+			},
+			want: []rbacv1.PolicyRule{
+				{Verbs: []string{"get", "post"}, NonResourceURLs: []string{"/healthz/*"}},
+				{Verbs: []string{"get"}, NonResourceURLs: []string{"/metrics"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Flatten(tt.rules); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Flatten() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClusterRoleRules(t *testing.T) {
+	aggregate := func(label string) *rbacv1.AggregationRule {
+		return &rbacv1.AggregationRule{
+			ClusterRoleSelectors: []metav1.LabelSelector{
+				{MatchLabels: map[string]string{label: "true"}},
+			},
+		}
+	}
+	cache := clusterRoleCache{
+		"view": {
+			ObjectMeta:      metav1.ObjectMeta{Name: "view", Labels: map[string]string{"aggregate-to-edit": "true"}},
+			AggregationRule: aggregate("aggregate-to-view"),
+			Rules:           []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"pods"})},
+		},
+		"edit": {
+			ObjectMeta:      metav1.ObjectMeta{Name: "edit"},
+			AggregationRule: aggregate("aggregate-to-edit"),
+		},
+		"view-extra": {
+			ObjectMeta: metav1.ObjectMeta{Name: "view-extra", Labels: map[string]string{"aggregate-to-view": "true"}},
+			Rules:      []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"configmaps"})},
+		},
+		"edit-extra": {
+			ObjectMeta: metav1.ObjectMeta{Name: "edit-extra", Labels: map[string]string{"aggregate-to-edit": "true"}},
+			Rules:      []rbacv1.PolicyRule{rule([]string{"update"}, []string{""}, []string{"pods"})},
+		},
+		"loop": {
+			ObjectMeta:      metav1.ObjectMeta{Name: "loop", Labels: map[string]string{"loop": "true"}},
+			AggregationRule: aggregate("loop"),
+			Rules:           []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"nodes"})},
+		},
+	}
+	resolver := NewResolver(cache, nil)
+
+	tests := []struct {
+		name        string
+		clusterRole string
+		want        []rbacv1.PolicyRule
+	}{
+		{
+			name:        "plain",
+			clusterRole: "edit-extra",
+			want:        []rbacv1.PolicyRule{rule([]string{"update"}, []string{""}, []string{"pods"})},
+		},
+		{
+			name:        "aggregated rules not yet filled in",
+			clusterRole: "view",
+			want: []rbacv1.PolicyRule{
+				rule([]string{"get"}, []string{""}, []string{"configmaps"}),
+				rule([]string{"get"}, []string{""}, []string{"pods"}),
+			},
+		},
+		{
+			name:        "nested aggregation",
+			clusterRole: "edit",
+			want: []rbacv1.PolicyRule{
+				rule([]string{"get"}, []string{""}, []string{"configmaps"}),
+				rule([]string{"get"}, []string{""}, []string{"pods"}),
+				rule([]string{"update"}, []string{""}, []string{"pods"}),
+			},
+		},
+		{
+			name:        "aggregating itself",
+			clusterRole: "loop",
+			want:        []rbacv1.PolicyRule{rule([]string{"get"}, []string{""}, []string{"nodes"})},
+		},
+		{
+			name:        "missing",
+			clusterRole: "missing",
+			want:        nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolver.ClusterRoleRules(tt.clusterRole)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Slice(got, func(i, j int) bool {
+				return got[i].String() < got[j].String()
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ClusterRoleRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type clusterRoleCache map[string]*rbacv1.ClusterRole
+
+func (c clusterRoleCache) Get(name string) (*rbacv1.ClusterRole, error) {
+	if clusterRole, ok := c[name]; ok {
+		return clusterRole, nil
+	}
+	return nil, errors.NewNotFound(rbacv1.Resource("clusterroles"), name)
+}
+
+func (c clusterRoleCache) List(selector labels.Selector) ([]*rbacv1.ClusterRole, error) {
+	var result []*rbacv1.ClusterRole
+	for _, clusterRole := range c {
+		if selector.Matches(labels.Set(clusterRole.Labels)) {
+			result = append(result, clusterRole)
+		}
+	}
+	return result, nil
+}
+
+func (c clusterRoleCache) AddIndexer(indexName string, indexer rbaccontroller.ClusterRoleIndexer) {}
+
+func (c clusterRoleCache) GetByIndex(indexName, key string) ([]*rbacv1.ClusterRole, error) {
+	return nil, nil
+}
@@ -0,0 +1,240 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	klum "github.com/ibuildthecloud/klum/pkg/apis/klum.cattle.io/v1alpha1"
+	"github.com/sirupsen/logrus"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// garbageCollectors are the users deleting Kubeconfigs owned by deleted token
+// Secrets, depending on how the controller-manager is configured
+var garbageCollectors = map[string]bool{
+	"system:serviceaccount:kube-system:generic-garbage-collector": true,
+	"system:kube-controller-manager":                              true,
+}
+
+type admitFunc func(req *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error)
+
+func (s *server) serveValidate(rw http.ResponseWriter, req *http.Request) {
+	serve(rw, req, s.validate)
+}
+
+func (s *server) serveMutate(rw http.ResponseWriter, req *http.Request) {
+	serve(rw, req, s.mutate)
+}
+
+func serve(rw http.ResponseWriter, req *http.Request, admit admitFunc) {
+	review := &admissionv1.AdmissionReview{}
+	if err := json.NewDecoder(req.Body).Decode(review); err != nil || review.Request == nil {
+		http.Error(rw, "invalid AdmissionReview", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := admit(review.Request)
+	if err != nil {
+		logrus.Errorf("Failed to admit %s %s: %v", review.Request.Resource.Resource, review.Request.Name, err)
+		resp = deny(http.StatusInternalServerError, err.Error())
+	}
+	resp.UID = review.Request.UID
+
+	review.Request = nil
+	review.Response = resp
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(review); err != nil {
+		logrus.Errorf("Failed to write AdmissionReview: %v", err)
+	}
+}
+
+func (s *server) validate(req *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error) {
+	switch req.Resource.Resource {
+	case "users":
+		return s.validateUser(req)
+	case "credentials":
+		return s.validateCredential(req)
+	case "accessrequests":
+		return s.validateApproval(req)
+	case "kubeconfigs":
+		if req.UserInfo.Username == s.cfg.ControllerUsername ||
+			req.Operation == admissionv1.Delete && garbageCollectors[req.UserInfo.Username] {
+			return allow(), nil
+		}
+		return deny(http.StatusForbidden, "kubeconfigs are managed by klum, change the user instead"), nil
+	}
+	return allow(), nil
+}
+
+func (s *server) validateUser(req *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error) {
+	user := &klum.User{}
+	if err := json.Unmarshal(req.Object.Raw, user); err != nil {
+		return deny(http.StatusBadRequest, err.Error()), nil
+	}
+
+	if req.Operation == admissionv1.Update {
+		old := &klum.User{}
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return deny(http.StatusBadRequest, err.Error()), nil
+		}
+		// users admitted before a policy changed can still be updated or
+		// deleted as long as their spec doesn't change
+		if user.DeletionTimestamp != nil || equality.Semantic.DeepEqual(old.Spec, user.Spec) {
+			return allow(), nil
+		}
+	}
+
+	problems, err := s.validator.Validate(user)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return deny(http.StatusUnprocessableEntity, strings.Join(problems, "; ")), nil
+	}
+	return allow(), nil
+}
+
+func (s *server) validateCredential(req *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error) {
+	cred := &klum.Credential{}
+	if err := json.Unmarshal(req.Object.Raw, cred); err != nil {
+		return deny(http.StatusBadRequest, err.Error()), nil
+	}
+
+	if req.Operation == admissionv1.Update {
+		old := &klum.Credential{}
+		if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+			return deny(http.StatusBadRequest, err.Error()), nil
+		}
+		// like users, credentials admitted before a policy changed can still
+		// be updated or deleted as long as their spec doesn't change
+		if cred.DeletionTimestamp != nil || equality.Semantic.DeepEqual(old.Spec, cred.Spec) {
+			return allow(), nil
+		}
+	}
+
+	problems, err := s.validator.ValidateCredential(cred)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return deny(http.StatusUnprocessableEntity, strings.Join(problems, "; ")), nil
+	}
+	return allow(), nil
+}
+
+// validateApproval only admits approvers setting themselves as approvedBy in
+// the status of an access request. Who can approve at all is up to RBAC on
+// accessrequests/status, the rest of the status belongs to the controller.
+func (s *server) validateApproval(req *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error) {
+	if req.SubResource != "status" || req.UserInfo.Username == s.cfg.ControllerUsername {
+		return allow(), nil
+	}
+
+	accessRequest := &klum.AccessRequest{}
+	if err := json.Unmarshal(req.Object.Raw, accessRequest); err != nil {
+		return deny(http.StatusBadRequest, err.Error()), nil
+	}
+	old := &klum.AccessRequest{}
+	if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+		return deny(http.StatusBadRequest, err.Error()), nil
+	}
+
+	status := old.Status
+	status.ApprovedBy = accessRequest.Status.ApprovedBy
+	if !equality.Semantic.DeepEqual(status, accessRequest.Status) {
+		return deny(http.StatusForbidden, "approvedBy is the only field of the status of an access request "+
+			"that can be changed"), nil
+	}
+	// clearing the approver revokes the request
+	if accessRequest.Status.ApprovedBy == old.Status.ApprovedBy || accessRequest.Status.ApprovedBy == "" {
+		return allow(), nil
+	}
+
+	problems, err := s.validator.ValidateApprover(accessRequest, req.UserInfo)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return deny(http.StatusUnprocessableEntity, strings.Join(problems, "; ")), nil
+	}
+	return allow(), nil
+}
+
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// mutate defaults the fields of users that are implied when empty
+func (s *server) mutate(req *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, error) {
+	if req.Resource.Resource != "users" {
+		return allow(), nil
+	}
+
+	user := &klum.User{}
+	if err := json.Unmarshal(req.Object.Raw, user); err != nil {
+		return deny(http.StatusBadRequest, err.Error()), nil
+	}
+	if user.DeletionTimestamp != nil {
+		return allow(), nil
+	}
+
+	var raw struct {
+		Spec *json.RawMessage `json:"spec"`
+	}
+	if err := json.Unmarshal(req.Object.Raw, &raw); err != nil {
+		return deny(http.StatusBadRequest, err.Error()), nil
+	}
+
+	var patch []patchOperation
+	if raw.Spec == nil {
+		patch = append(patch, patchOperation{Op: "add", Path: "/spec", Value: map[string]interface{}{}})
+	}
+	if user.Spec.Enabled == nil {
+		patch = append(patch, patchOperation{Op: "add", Path: "/spec/enabled", Value: true})
+	}
+	if user.Spec.Schedule != nil && user.Spec.Schedule.Timezone == "" {
+		patch = append(patch, patchOperation{Op: "add", Path: "/spec/schedule/timezone", Value: "UTC"})
+	}
+
+	resp := allow()
+	if len(patch) == 0 {
+		return resp, nil
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	patchType := admissionv1.PatchTypeJSONPatch
+	resp.Patch = data
+	resp.PatchType = &patchType
+	return resp, nil
+}
+
+func allow() *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: true,
+	}
+}
+
+func deny(code int32, message string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    code,
+			Message: message,
+		},
+	}
+}
+
+// ServiceAccountUsername is the name the apiserver authenticates a ServiceAccount as
+func ServiceAccountUsername(namespace, name string) string {
+	return fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name)
+}
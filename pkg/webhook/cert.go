@@ -0,0 +1,167 @@
+package webhook
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	caKey = "ca.crt"
+
+	caValidity   = 10 * 365 * 24 * time.Hour
+	certValidity = 365 * 24 * time.Hour
+	// certificates are renewed when they expire within renewBefore
+	renewBefore = 30 * 24 * time.Hour
+	// storeAttempts is how often storing a certificate is tried while other
+	// replicas store theirs at the same time
+	storeAttempts = 5
+)
+
+// ensureCert returns the serving certificate stored in the Secret of the
+// webhook and the CA that signed it. A new certificate is generated and stored
+// when there is none, or it is for another name or about to expire.
+func ensureCert(ctx context.Context, secrets corev1.SecretInterface, cfg Config, now time.Time) (*tls.Certificate, []byte, error) {
+	for attempt := 1; ; attempt++ {
+		cert, caPEM, err := loadOrStoreCert(ctx, secrets, cfg, now)
+		if (errors.IsAlreadyExists(err) || errors.IsConflict(err)) && attempt < storeAttempts {
+			// another replica stored a certificate first, use that one
+			continue
+		}
+		return cert, caPEM, err
+	}
+}
+
+func loadOrStoreCert(ctx context.Context, secrets corev1.SecretInterface, cfg Config, now time.Time) (*tls.Certificate, []byte, error) {
+	secret, err := secrets.Get(ctx, cfg.SecretName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		secret = nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	if secret != nil {
+		cert, err := parseCert(secret, cfg.dnsName(), now)
+		if err == nil {
+			return cert, secret.Data[caKey], nil
+		}
+		logrus.Infof("Renewing webhook certificate: %v", err)
+	}
+
+	caPEM, certPEM, keyPEM, err := generateCert(cfg.dnsName(), now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data := map[string][]byte{
+		caKey:               caPEM,
+		v1.TLSCertKey:       certPEM,
+		v1.TLSPrivateKeyKey: keyPEM,
+	}
+	if secret == nil {
+		_, err = secrets.Create(ctx, &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cfg.SecretName,
+				Namespace: cfg.Namespace,
+			},
+			Type: v1.SecretTypeTLS,
+			Data: data,
+		}, metav1.CreateOptions{})
+	} else {
+		secret = secret.DeepCopy()
+		secret.Data = data
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	return &cert, caPEM, err
+}
+
+func parseCert(secret *v1.Secret, dnsName string, now time.Time) (*tls.Certificate, error) {
+	cert, err := tls.X509KeyPair(secret.Data[v1.TLSCertKey], secret.Data[v1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, err
+	}
+	if len(secret.Data[caKey]) == 0 {
+		return nil, fmt.Errorf("secret %s has no %s", secret.Name, caKey)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := leaf.VerifyHostname(dnsName); err != nil {
+		return nil, err
+	}
+	if now.Add(renewBefore).After(leaf.NotAfter) {
+		return nil, fmt.Errorf("certificate expires at %s", leaf.NotAfter)
+	}
+
+	return &cert, nil
+}
+
+// generateCert returns a new self-signed CA and a serving certificate for
+// dnsName signed by it, all PEM encoded
+func generateCert(dnsName string, now time.Time) ([]byte, []byte, []byte, error) {
+	caPrivateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(now.UnixNano()),
+		Subject:               pkix.Name{CommonName: "klum-webhook-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, caPrivateKey.Public(), caPrivateKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cert := &x509.Certificate{
+		SerialNumber: big.NewInt(now.UnixNano() + 1),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(certValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, cert, ca, key.Public(), caPrivateKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		nil
+}
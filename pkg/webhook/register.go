@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"context"
+
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	admissionclient "k8s.io/client-go/kubernetes/typed/admissionregistration/v1"
+)
+
+const (
+	validatePath = "/validate"
+	mutatePath   = "/mutate"
+)
+
+// register creates or updates the webhook configurations pointing the
+// apiserver at the Service of the webhook
+func register(ctx context.Context, client admissionclient.AdmissionregistrationV1Interface, cfg Config, caPEM []byte) error {
+	validating := &admissionv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: cfg.Name,
+		},
+		Webhooks: []admissionv1.ValidatingWebhook{
+			cfg.validatingWebhook("users.klum.cattle.io", caPEM, "users",
+				admissionv1.Create, admissionv1.Update),
+			cfg.validatingWebhook("credentials.klum.cattle.io", caPEM, "credentials",
+				admissionv1.Create, admissionv1.Update),
+			cfg.validatingWebhook("accessrequests.klum.cattle.io", caPEM, "accessrequests/status",
+				admissionv1.Update),
+			cfg.validatingWebhook("kubeconfigs.klum.cattle.io", caPEM, "kubeconfigs",
+				admissionv1.Create, admissionv1.Update, admissionv1.Delete),
+		},
+	}
+
+	existing, err := client.ValidatingWebhookConfigurations().Get(ctx, cfg.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = client.ValidatingWebhookConfigurations().Create(ctx, validating, metav1.CreateOptions{})
+	} else if err == nil {
+		existing.Webhooks = validating.Webhooks
+		_, err = client.ValidatingWebhookConfigurations().Update(ctx, existing, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	mutating := &admissionv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: cfg.Name,
+		},
+		Webhooks: []admissionv1.MutatingWebhook{
+			cfg.mutatingWebhook("users.klum.cattle.io", caPEM, "users",
+				admissionv1.Create, admissionv1.Update),
+		},
+	}
+
+	existingMutating, err := client.MutatingWebhookConfigurations().Get(ctx, cfg.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = client.MutatingWebhookConfigurations().Create(ctx, mutating, metav1.CreateOptions{})
+	} else if err == nil {
+		existingMutating.Webhooks = mutating.Webhooks
+		_, err = client.MutatingWebhookConfigurations().Update(ctx, existingMutating, metav1.UpdateOptions{})
+	}
+	return err
+}
+
+func (c Config) validatingWebhook(name string, caPEM []byte, resource string, ops ...admissionv1.OperationType) admissionv1.ValidatingWebhook {
+	sideEffects := admissionv1.SideEffectClassNone
+	failurePolicy := admissionv1.FailurePolicyType(c.FailurePolicy)
+	return admissionv1.ValidatingWebhook{
+		Name:                    name,
+		ClientConfig:            c.clientConfig(validatePath, caPEM),
+		Rules:                   rules(resource, ops),
+		FailurePolicy:           &failurePolicy,
+		SideEffects:             &sideEffects,
+		AdmissionReviewVersions: []string{"v1"},
+	}
+}
+
+func (c Config) mutatingWebhook(name string, caPEM []byte, resource string, ops ...admissionv1.OperationType) admissionv1.MutatingWebhook {
+	sideEffects := admissionv1.SideEffectClassNone
+	failurePolicy := admissionv1.FailurePolicyType(c.FailurePolicy)
+	return admissionv1.MutatingWebhook{
+		Name:                    name,
+		ClientConfig:            c.clientConfig(mutatePath, caPEM),
+		Rules:                   rules(resource, ops),
+		FailurePolicy:           &failurePolicy,
+		SideEffects:             &sideEffects,
+		AdmissionReviewVersions: []string{"v1"},
+	}
+}
+
+func (c Config) clientConfig(path string, caPEM []byte) admissionv1.WebhookClientConfig {
+	return admissionv1.WebhookClientConfig{
+		Service: &admissionv1.ServiceReference{
+			Namespace: c.Namespace,
+			Name:      c.ServiceName,
+			Path:      &path,
+		},
+		CABundle: caPEM,
+	}
+}
+
+func rules(resource string, ops []admissionv1.OperationType) []admissionv1.RuleWithOperations {
+	scope := admissionv1.ClusterScope
+	return []admissionv1.RuleWithOperations{
+		{
+			Operations: ops,
+			Rule: admissionv1.Rule{
+				APIGroups:   []string{"klum.cattle.io"},
+				APIVersions: []string{"v1alpha1"},
+				Resources:   []string{resource},
+				Scope:       &scope,
+			},
+		},
+	}
+}
@@ -0,0 +1,135 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ibuildthecloud/klum/pkg/controllers/user"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// renewInterval is how often the serving certificate is checked for renewal
+const renewInterval = time.Hour
+
+type Config struct {
+	// Name of the webhook configurations
+	Name string
+	// Namespace, ServiceName and SecretName locate the Service of the webhook
+	// and the Secret storing its certificate
+	Namespace   string
+	ServiceName string
+	SecretName  string
+	Port        int
+	// FailurePolicy is either Fail or Ignore
+	FailurePolicy string
+	// ControllerUsername is the user the controller runs as, the only user
+	// allowed to change Kubeconfigs
+	ControllerUsername string
+}
+
+func (c Config) dnsName() string {
+	return fmt.Sprintf("%s.%s.svc", c.ServiceName, c.Namespace)
+}
+
+type server struct {
+	cfg       Config
+	validator *user.Validator
+
+	lock  sync.RWMutex
+	cert  *tls.Certificate
+	caPEM []byte
+}
+
+// Run serves the webhook until the context is done. The serving certificate is
+// generated, and renewed, by the webhook itself.
+func Run(ctx context.Context, cfg Config, restConfig *rest.Config, validator *user.Validator) error {
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+
+	s := &server{
+		cfg:       cfg,
+		validator: validator,
+	}
+	if err := s.ensureCert(ctx, client); err != nil {
+		return err
+	}
+	go s.renew(ctx, client)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(validatePath, s.serveValidate)
+	mux.HandleFunc(mutatePath, s.serveMutate)
+
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.Port),
+		Handler: mux,
+		TLSConfig: &tls.Config{
+			GetCertificate: s.getCertificate,
+		},
+	}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	logrus.Infof("Serving webhook on %s", srv.Addr)
+	if err := srv.ListenAndServeTLS("", ""); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// ensureCert loads the current certificate and registers the webhook with its
+// CA when it changed
+func (s *server) ensureCert(ctx context.Context, client kubernetes.Interface) error {
+	cert, caPEM, err := ensureCert(ctx, client.CoreV1().Secrets(s.cfg.Namespace), s.cfg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	s.lock.RLock()
+	changed := !bytes.Equal(caPEM, s.caPEM)
+	s.lock.RUnlock()
+	if !changed {
+		return nil
+	}
+
+	if err := register(ctx, client.AdmissionregistrationV1(), s.cfg, caPEM); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	s.cert, s.caPEM = cert, caPEM
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *server) renew(ctx context.Context, client kubernetes.Interface) {
+	ticker := time.NewTicker(renewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ensureCert(ctx, client); err != nil {
+				logrus.Errorf("Failed to renew webhook certificate: %v", err)
+			}
+		}
+	}
+}
+
+func (s *server) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.cert, nil
+}